@@ -5,14 +5,23 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
+	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
 	"islamdiplom/internal/config"
 	"islamdiplom/internal/db"
+	"islamdiplom/internal/domain"
 	httpapi "islamdiplom/internal/http"
+	"islamdiplom/internal/mailer"
+	"islamdiplom/internal/repository"
+	"islamdiplom/internal/repository/memory"
 	"islamdiplom/internal/repository/postgres"
 	"islamdiplom/internal/service"
 )
@@ -24,6 +33,7 @@ func main() {
 	var err error
 	if cfg.LogMode == "prod" {
 		logger, err = zap.NewProduction()
+		gin.SetMode(gin.ReleaseMode)
 	} else {
 		logger, err = zap.NewDevelopment()
 	}
@@ -33,6 +43,9 @@ func main() {
 	defer func() {
 		_ = logger.Sync()
 	}()
+	// Work outside a request, such as background pruning, logs through the
+	// global logger.
+	zap.ReplaceGlobals(logger)
 
 	dbConn, err := db.Open(context.Background(), cfg.DatabaseURL)
 	if err != nil {
@@ -46,26 +59,209 @@ func main() {
 
 	eventRepo := postgres.NewEventRepository(dbConn)
 	venueRepo := postgres.NewVenueRepository(dbConn)
+	hallRepo := postgres.NewHallRepository(dbConn)
 	categoryRepo := postgres.NewCategoryRepository(dbConn)
 	userRepo := postgres.NewUserRepository(dbConn)
 	bookingRepo := postgres.NewBookingRepository(dbConn)
+	refreshTokenRepo := postgres.NewRefreshTokenRepository(dbConn)
+	passwordResetRepo := postgres.NewPasswordResetRepository(dbConn)
+	emailVerificationRepo := postgres.NewEmailVerificationRepository(dbConn)
+	searchRepo := postgres.NewSearchRepository(dbConn)
+	mfaRepo := postgres.NewMFARepository(dbConn)
+	oidcRepo := postgres.NewOIDCRepository(dbConn)
+	apiKeyRepo := postgres.NewAPIKeyRepository(dbConn)
+	sessionRepo := postgres.NewSessionRepository(dbConn)
+	auditRepo := postgres.NewAuditRepository(dbConn)
 
-	eventService := service.NewEventService(eventRepo, venueRepo)
-	venueService := service.NewVenueService(venueRepo)
+	auditLog := service.NewAuditLog(auditRepo)
+
+	eventService := service.NewEventService(
+		eventRepo,
+		venueRepo,
+		hallRepo,
+		auditLog,
+		mustDuration(logger, "EVENT_SETUP_BUFFER", cfg.EventSetupBuffer),
+		mustDuration(logger, "EVENT_CLEANUP_BUFFER", cfg.EventCleanupBuffer),
+	)
+	venueService := service.NewVenueService(venueRepo, hallRepo, auditLog)
+	hallService := service.NewHallService(hallRepo, venueRepo, auditLog)
 	categoryService := service.NewCategoryService(categoryRepo)
-	bookingService := service.NewBookingService(bookingRepo, eventRepo)
+	searchService := service.NewSearchService(searchRepo)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo, auditLog)
+
+	requireVerified, err := strconv.ParseBool(cfg.RequireVerifiedEmail)
+	if err != nil {
+		logger.Fatal("invalid REQUIRE_VERIFIED_EMAIL", zap.Error(err))
+	}
+	bookingService := service.NewBookingService(bookingRepo, eventRepo, hallRepo, userRepo, auditLog, requireVerified)
+
+	if cfg.SMTPAddr == "" && cfg.LogMode == "prod" {
+		logger.Fatal("SMTP_ADDR is required in production")
+	}
+	var mail mailer.Mailer = mailer.NewLogMailer(logger)
+	if cfg.SMTPAddr != "" {
+		mail = mailer.NewSMTPMailer(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+
+	verificationService := service.NewVerificationService(
+		userRepo,
+		emailVerificationRepo,
+		mail,
+		mustDuration(logger, "EMAIL_VERIFICATION_TTL", cfg.EmailVerificationTTL),
+		mustDuration(logger, "EMAIL_VERIFICATION_RESEND_INTERVAL", cfg.EmailVerificationResendInterval),
+		cfg.EmailVerificationURL,
+	)
 
-	ttl, err := time.ParseDuration(cfg.JWTTTL)
+	var loginAttemptRepo repository.LoginAttemptRepository = postgres.NewLoginAttemptRepository(dbConn)
+	if cfg.LoginAttemptStore == "memory" {
+		loginAttemptRepo = memory.NewLoginAttemptRepository()
+	}
+	loginGuard := service.NewLoginGuard(loginAttemptRepo, auditLog, service.LoginPolicy{
+		MaxEmailFailures: mustInt(logger, "LOGIN_MAX_EMAIL_FAILURES", cfg.LoginMaxEmailFailures),
+		MaxIPFailures:    mustInt(logger, "LOGIN_MAX_IP_FAILURES", cfg.LoginMaxIPFailures),
+		Window:           mustDuration(logger, "LOGIN_FAILURE_WINDOW", cfg.LoginFailureWindow),
+	})
+
+	// Buckets are per process unless shared through Postgres, so multi-replica
+	// deployments should use the postgres store.
+	var rateLimitRepo repository.RateLimitRepository = memory.NewRateLimitRepository()
+	if cfg.RateLimitStore == "postgres" {
+		rateLimitRepo = postgres.NewRateLimitRepository(dbConn)
+	}
+	rateLimiter := service.NewRateLimiter(rateLimitRepo, []domain.RateLimitPolicy{
+		mustRateLimit(logger, domain.RateLimitAuth, "RATE_LIMIT_AUTH", cfg.RateLimitAuth),
+		mustRateLimit(logger, domain.RateLimitBookings, "RATE_LIMIT_BOOKINGS", cfg.RateLimitBookings),
+		mustRateLimit(logger, domain.RateLimitCatalogue, "RATE_LIMIT_CATALOGUE", cfg.RateLimitCatalogue),
+		mustRateLimit(logger, domain.RateLimitSession, "RATE_LIMIT_SESSION", cfg.RateLimitSession),
+	})
+
+	idempotencyService := service.NewIdempotencyService(
+		postgres.NewIdempotencyRepository(dbConn),
+		mustDuration(logger, "IDEMPOTENCY_TTL", cfg.IdempotencyTTL),
+	)
+
+	hasher, err := service.NewPasswordHasher(
+		cfg.PasswordHashAlgorithm,
+		mustInt(logger, "BCRYPT_COST", cfg.BcryptCost),
+		service.Argon2Params{
+			Memory:  uint32(mustInt(logger, "ARGON2_MEMORY_KB", cfg.Argon2Memory)),
+			Time:    uint32(mustInt(logger, "ARGON2_TIME", cfg.Argon2Time)),
+			Threads: uint8(mustInt(logger, "ARGON2_THREADS", cfg.Argon2Threads)),
+		},
+	)
+	if err != nil {
+		logger.Fatal("invalid password hashing settings", zap.Error(err))
+	}
+	breached, err := service.LoadBreachedPasswords(cfg.PasswordBreachedList)
 	if err != nil {
-		logger.Fatal("invalid JWT_TTL", zap.Error(err))
+		logger.Fatal("invalid PASSWORD_BREACHED_LIST", zap.Error(err))
 	}
-	authService := service.NewAuthService(userRepo, cfg.JWTSecret, ttl)
+	passwordPolicy := service.NewPasswordPolicy(
+		mustInt(logger, "PASSWORD_MIN_LENGTH", cfg.PasswordMinLength),
+		mustInt(logger, "PASSWORD_MAX_LENGTH", cfg.PasswordMaxLength),
+		breached,
+	)
+
+	tokenKeys := service.NewHMACTokenKeys(cfg.JWTSecret)
+	if cfg.JWTKeysDir != "" {
+		tokenKeys, err = service.LoadTokenKeys(cfg.JWTKeysDir, mustDuration(logger, "JWT_KEY_PUBLISH_DELAY", cfg.JWTKeyPublishDelay))
+		if err != nil {
+			logger.Fatal("invalid JWT_KEYS_DIR", zap.Error(err))
+		}
+	}
+	if cfg.LogMode == "prod" && tokenKeys.UsesDefaultSecret(config.DefaultJWTSecret) {
+		logger.Fatal("JWT_SECRET is the default value; set it or configure JWT_KEYS_DIR")
+	}
+
+	mfaService := service.NewMFAService(mfaRepo, userRepo, hasher, cfg.MFAIssuer, strings.Split(cfg.MFARequiredRoles, ","), auditLog)
+
+	authService := service.NewAuthService(
+		userRepo,
+		refreshTokenRepo,
+		sessionRepo,
+		verificationService,
+		loginGuard,
+		hasher,
+		passwordPolicy,
+		mfaService,
+		tokenKeys,
+		auditLog,
+		mustDuration(logger, "JWT_TTL", cfg.JWTTTL),
+		mustDuration(logger, "REFRESH_TTL", cfg.RefreshTTL),
+	)
+	oidcProviders := make([]service.OIDCProviderConfig, 0, len(cfg.OIDCProviders))
+	for _, provider := range cfg.OIDCProviders {
+		if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			logger.Fatal("incomplete OIDC provider settings", zap.String("provider", provider.Name))
+		}
+		oidcProviders = append(oidcProviders, service.OIDCProviderConfig{
+			Name:         provider.Name,
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+			Scopes:       strings.Fields(provider.Scopes),
+			AuthURL:      provider.AuthURL,
+			TokenURL:     provider.TokenURL,
+			JWKSURL:      provider.JWKSURL,
+		})
+	}
+	oidcService := service.NewOIDCService(authService, userRepo, oidcRepo, &http.Client{Timeout: 10 * time.Second}, oidcProviders)
+
+	profileService := service.NewProfileService(userRepo, bookingRepo, eventRepo, hasher, auditLog)
+
+	passwordService := service.NewPasswordService(
+		authService,
+		userRepo,
+		passwordResetRepo,
+		mail,
+		mustDuration(logger, "PASSWORD_RESET_TTL", cfg.PasswordResetTTL),
+		cfg.PasswordResetURL,
+		auditLog,
+	)
 
 	if err := db.ApplyMigrations(context.Background(), dbConn, cfg.MigrationsDir, logger); err != nil {
 		logger.Fatal("migration error", zap.Error(err))
 	}
+	if err := authService.GrantAdmins(context.Background(), splitList(cfg.AdminEmails)); err != nil {
+		logger.Fatal("invalid ADMIN_EMAILS", zap.Error(err))
+	}
+
+	allowCredentials, err := strconv.ParseBool(cfg.CORSAllowCredentials)
+	if err != nil {
+		logger.Fatal("invalid CORS_ALLOW_CREDENTIALS", zap.Error(err))
+	}
+	allowedOrigins := splitList(cfg.CORSAllowedOrigins)
+	if allowCredentials && slices.Contains(allowedOrigins, "*") {
+		logger.Fatal("CORS_ALLOWED_ORIGINS=* cannot be combined with CORS_ALLOW_CREDENTIALS=true; list the origins instead")
+	}
+	// Browsers cache HSTS per host, and includeSubDomains on localhost would
+	// force HTTPS on every other service developers run there.
+	hstsMaxAge := mustDuration(logger, "HSTS_MAX_AGE", cfg.HSTSMaxAge)
+	if cfg.LogMode != "prod" {
+		hstsMaxAge = 0
+	}
+	routerOptions := httpapi.RouterOptions{
+		Logger:         logger,
+		TrustedProxies: splitList(cfg.TrustedProxies),
+		CORS: httpapi.CORSConfig{
+			AllowedOrigins:   allowedOrigins,
+			AllowedMethods:   splitList(cfg.CORSAllowedMethods),
+			AllowedHeaders:   splitList(cfg.CORSAllowedHeaders),
+			ExposedHeaders:   splitList(cfg.CORSExposedHeaders),
+			AllowCredentials: allowCredentials,
+			MaxAge:           mustDuration(logger, "CORS_MAX_AGE", cfg.CORSMaxAge),
+		},
+		SecurityHeaders: httpapi.SecurityHeadersConfig{
+			HSTSMaxAge:            hstsMaxAge,
+			ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+		},
+	}
 
-	router := httpapi.NewRouter(eventService, venueService, categoryService, authService, bookingService)
+	router, err := httpapi.NewRouter(eventService, venueService, hallService, categoryService, authService, mfaService, oidcService, profileService, passwordService, verificationService, loginGuard, apiKeyService, auditLog, rateLimiter, idempotencyService, bookingService, searchService, routerOptions)
+	if err != nil {
+		logger.Fatal("invalid TRUSTED_PROXIES", zap.Error(err))
+	}
 
 	server := &http.Server{
 		Addr:         cfg.HTTPAddr,
@@ -75,6 +271,13 @@ func main() {
 		IdleTimeout:  30 * time.Second,
 	}
 
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	if cfg.JWTKeysDir != "" {
+		go reloadTokenKeys(backgroundCtx, logger, tokenKeys, mustDuration(logger, "JWT_KEYS_RELOAD_INTERVAL", cfg.JWTKeysReloadInterval))
+	}
+	go pruneExpired(backgroundCtx, logger, time.Minute, rateLimiter, idempotencyService)
+
 	go func() {
 		logger.Info("http server listening", zap.String("addr", cfg.HTTPAddr))
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -92,3 +295,75 @@ func main() {
 		logger.Warn("http server shutdown error", zap.Error(err))
 	}
 }
+
+// reloadTokenKeys picks up keys added to or removed from JWT_KEYS_DIR, which
+// is how signing keys are rotated without a restart.
+func reloadTokenKeys(ctx context.Context, logger *zap.Logger, keys *service.TokenKeys, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := keys.Reload(); err != nil {
+				logger.Warn("jwt keys reload error", zap.Error(err))
+			}
+		}
+	}
+}
+
+type pruner interface {
+	Prune(ctx context.Context) error
+}
+
+func pruneExpired(ctx context.Context, logger *zap.Logger, interval time.Duration, pruners ...pruner) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, p := range pruners {
+				if err := p.Prune(ctx); err != nil {
+					logger.Warn("prune error", zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+func mustRateLimit(logger *zap.Logger, name, key, value string) domain.RateLimitPolicy {
+	policy, err := service.ParseRateLimitPolicy(name, value)
+	if err != nil {
+		logger.Fatal("invalid "+key, zap.Error(err))
+	}
+	return policy
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func mustDuration(logger *zap.Logger, key, value string) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Fatal("invalid "+key, zap.Error(err))
+	}
+	return duration
+}
+
+func mustInt(logger *zap.Logger, key, value string) int {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Fatal("invalid "+key, zap.Error(err))
+	}
+	return number
+}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Ticket struct {
+	BookingID  uuid.UUID `json:"bookingId"`
+	EventID    uuid.UUID `json:"eventId"`
+	EventTitle string    `json:"eventTitle"`
+	StartAt    time.Time `json:"startAt"`
+	Seat       string    `json:"seat"`
+	Status     string    `json:"status"`
+}
+
+type AccountExport struct {
+	ExportedAt time.Time `json:"exportedAt"`
+	Profile    User      `json:"profile"`
+	Bookings   []Booking `json:"bookings"`
+	Tickets    []Ticket  `json:"tickets"`
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditCancel  = "cancel"
+	AuditRevoke  = "revoke"
+	AuditUnlock  = "unlock"
+	AuditEnable  = "enable"
+	AuditDisable = "disable"
+	AuditReset   = "reset"
+
+	AuditRevokeSessions = "revoke_sessions"
+)
+
+const (
+	AuditEntityEvent    = "event"
+	AuditEntityVenue    = "venue"
+	AuditEntityHall     = "hall"
+	AuditEntityBooking  = "booking"
+	AuditEntityAPIKey   = "api_key"
+	AuditEntityLogin    = "login"
+	AuditEntityUser     = "user"
+	AuditEntityPassword = "password"
+	AuditEntityMFA      = "mfa"
+	AuditEntitySession  = "session"
+)
+
+// AuditEntry records one change. Diff maps each changed top-level field to
+// its old and new value.
+type AuditEntry struct {
+	ID         uuid.UUID       `json:"id"`
+	ActorID    *uuid.UUID      `json:"actorId"`
+	APIKeyID   *uuid.UUID      `json:"apiKeyId"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entityType"`
+	EntityID   string          `json:"entityId"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Diff       json.RawMessage `json:"diff"`
+	RequestID  string          `json:"requestId"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	ActorID    *uuid.UUID
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+}
@@ -7,13 +7,23 @@ import (
 )
 
 type Event struct {
-	ID          uuid.UUID `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	StartAt     time.Time `json:"startAt"`
-	EndAt       time.Time `json:"endAt"`
-	VenueID     uuid.UUID `json:"venueId"`
-	Published   bool      `json:"published"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID           uuid.UUID `json:"id"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	StartAt      time.Time `json:"startAt"`
+	EndAt        time.Time `json:"endAt"`
+	VenueID      uuid.UUID `json:"venueId"`
+	HallID       uuid.UUID `json:"hallId"`
+	Published    bool      `json:"published"`
+	Version      int       `json:"version"`
+	Timezone     string    `json:"timezone,omitempty"`
+	StartAtLocal string    `json:"startAtLocal,omitempty"`
+	EndAtLocal   string    `json:"endAtLocal,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+type NearbyEvent struct {
+	Event
+	DistanceKm float64 `json:"distanceKm"`
 }
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SeatRow struct {
+	Label string `json:"label"`
+	Seats int    `json:"seats"`
+}
+
+type Hall struct {
+	ID        uuid.UUID `json:"id"`
+	VenueID   uuid.UUID `json:"venueId"`
+	Name      string    `json:"name"`
+	Capacity  int       `json:"capacity"`
+	Layout    []SeatRow `json:"layout"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord remembers the response to a request sent with an
+// Idempotency-Key. StatusCode is zero while the first request is still being
+// handled; after LockedUntil a retry may take over such a reservation.
+type IdempotencyRecord struct {
+	Subject     string
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	LockedUntil time.Time
+}
+
+func (r IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
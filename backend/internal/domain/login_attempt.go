@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LoginAttempt struct {
+	ID        uuid.UUID  `json:"id"`
+	Email     string     `json:"email"`
+	IP        string     `json:"ip"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ClearedAt *time.Time `json:"clearedAt"`
+}
+
+// LoginFailures counts the failed logins inside the guard window. The oldest
+// times tell when each count next drops.
+type LoginFailures struct {
+	ByEmail       int
+	ByIP          int
+	OldestByEmail time.Time
+	OldestByIP    time.Time
+}
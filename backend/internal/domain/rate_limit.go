@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	RateLimitAuth      = "auth"
+	RateLimitBookings  = "bookings"
+	RateLimitCatalogue = "catalogue"
+	RateLimitSession   = "session"
+)
+
+// RateLimitPolicy allows bursts of Limit requests, refilled evenly over
+// Window.
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+func (p RateLimitPolicy) Enabled() bool {
+	return p.Limit > 0 && p.Window > 0
+}
+
+func (p RateLimitPolicy) rate() float64 {
+	return float64(p.Limit) / p.Window.Seconds()
+}
+
+// RateLimitBucket is the stored state of one token bucket.
+type RateLimitBucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+type RateLimitDecision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// FullRateLimitBucket is the state of a key that has not been seen yet.
+func FullRateLimitBucket(policy RateLimitPolicy, now time.Time) RateLimitBucket {
+	return RateLimitBucket{Tokens: float64(policy.Limit), UpdatedAt: now}
+}
+
+// Take refills the bucket for the time elapsed since its last update and
+// spends one token if there is one.
+func (b RateLimitBucket) Take(policy RateLimitPolicy, now time.Time) (RateLimitBucket, RateLimitDecision) {
+	capacity := float64(policy.Limit)
+	rate := policy.rate()
+
+	tokens := b.Tokens
+	if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed*rate)
+	}
+
+	decision := RateLimitDecision{Limit: policy.Limit}
+	if tokens >= 1 {
+		tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+	decision.Remaining = int(math.Floor(tokens))
+	decision.Reset = secondsToDuration((capacity - tokens) / rate)
+
+	return RateLimitBucket{Tokens: tokens, UpdatedAt: now}, decision
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
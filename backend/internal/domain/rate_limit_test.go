@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimitBucketTake(t *testing.T) {
+	// One token per second, bursts of ten.
+	policy := RateLimitPolicy{Name: "test", Limit: 10, Window: 10 * time.Second}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		bucket     RateLimitBucket
+		now        time.Time
+		wantTokens float64
+		want       RateLimitDecision
+	}{
+		{
+			name:       "full bucket",
+			bucket:     FullRateLimitBucket(policy, start),
+			now:        start,
+			wantTokens: 9,
+			want:       RateLimitDecision{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name:       "empty bucket",
+			bucket:     RateLimitBucket{Tokens: 0, UpdatedAt: start},
+			now:        start,
+			wantTokens: 0,
+			want:       RateLimitDecision{Limit: 10, Remaining: 0, Reset: 10 * time.Second, RetryAfter: time.Second},
+		},
+		{
+			name:       "half a token refilled",
+			bucket:     RateLimitBucket{Tokens: 0, UpdatedAt: start},
+			now:        start.Add(500 * time.Millisecond),
+			wantTokens: 0.5,
+			want:       RateLimitDecision{Limit: 10, Remaining: 0, Reset: 9500 * time.Millisecond, RetryAfter: 500 * time.Millisecond},
+		},
+		{
+			name:       "refill over three seconds",
+			bucket:     RateLimitBucket{Tokens: 0, UpdatedAt: start},
+			now:        start.Add(3 * time.Second),
+			wantTokens: 2,
+			want:       RateLimitDecision{Allowed: true, Limit: 10, Remaining: 2, Reset: 8 * time.Second},
+		},
+		{
+			name:       "refill stops at the limit",
+			bucket:     RateLimitBucket{Tokens: 5, UpdatedAt: start},
+			now:        start.Add(time.Hour),
+			wantTokens: 9,
+			want:       RateLimitDecision{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name:       "fractional token left over",
+			bucket:     RateLimitBucket{Tokens: 1.5, UpdatedAt: start},
+			now:        start,
+			wantTokens: 0.5,
+			want:       RateLimitDecision{Allowed: true, Limit: 10, Remaining: 0, Reset: 9500 * time.Millisecond},
+		},
+		{
+			name:       "clock moved backwards",
+			bucket:     RateLimitBucket{Tokens: 5, UpdatedAt: start},
+			now:        start.Add(-time.Second),
+			wantTokens: 4,
+			want:       RateLimitDecision{Allowed: true, Limit: 10, Remaining: 4, Reset: 6 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, decision := tt.bucket.Take(policy, tt.now)
+			if decision != tt.want {
+				t.Fatalf("decision = %+v, want %+v", decision, tt.want)
+			}
+			if bucket.Tokens != tt.wantTokens {
+				t.Fatalf("tokens = %v, want %v", bucket.Tokens, tt.wantTokens)
+			}
+			if !bucket.UpdatedAt.Equal(tt.now) {
+				t.Fatalf("updated at %v, want %v", bucket.UpdatedAt, tt.now)
+			}
+		})
+	}
+}
+
+func TestRateLimitBucketDrains(t *testing.T) {
+	policy := RateLimitPolicy{Limit: 3, Window: time.Minute}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	bucket := FullRateLimitBucket(policy, now)
+	for i := 2; i >= 0; i-- {
+		var decision RateLimitDecision
+		bucket, decision = bucket.Take(policy, now)
+		if !decision.Allowed || decision.Remaining != i {
+			t.Fatalf("take %d = %+v, want allowed with %d remaining", 3-i, decision, i)
+		}
+	}
+
+	_, decision := bucket.Take(policy, now)
+	if decision.Allowed {
+		t.Fatal("fourth take was allowed")
+	}
+	if decision.RetryAfter != 20*time.Second || decision.Reset != time.Minute {
+		t.Fatalf("RetryAfter = %v, Reset = %v, want 20s and 1m", decision.RetryAfter, decision.Reset)
+	}
+}
@@ -0,0 +1,11 @@
+package domain
+
+import "github.com/google/uuid"
+
+type SearchResult struct {
+	Kind    string    `json:"kind"`
+	ID      uuid.UUID `json:"id"`
+	Title   string    `json:"title"`
+	Snippet string    `json:"snippet"`
+	Rank    float64   `json:"rank"`
+}
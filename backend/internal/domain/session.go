@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is one signed-in device. Its ID is also the family ID of the
+// refresh tokens it rotates through.
+type Session struct {
+	ID           uuid.UUID  `json:"id"`
+	UserID       uuid.UUID  `json:"userId"`
+	UserAgent    string     `json:"userAgent"`
+	IP           string     `json:"ip"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	LastActiveAt time.Time  `json:"lastActiveAt"`
+	RevokedAt    *time.Time `json:"-"`
+	Current      bool       `json:"current"`
+}
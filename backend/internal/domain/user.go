@@ -6,10 +6,25 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	RoleUser      = "user"
+	RoleOrganizer = "organizer"
+	RoleAdmin     = "admin"
+)
+
+var Roles = []string{RoleUser, RoleOrganizer, RoleAdmin}
+
+var Languages = []string{"ru", "kk", "en"}
+
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	ID                uuid.UUID  `json:"id"`
+	Email             string     `json:"email"`
+	PasswordHash      string     `json:"-"`
+	Role              string     `json:"role"`
+	Name              string     `json:"name"`
+	Phone             string     `json:"phone"`
+	PreferredLanguage string     `json:"preferredLanguage"`
+	EmailVerifiedAt   *time.Time `json:"emailVerifiedAt"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
 }
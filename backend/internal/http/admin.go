@@ -0,0 +1,192 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/service"
+)
+
+type AdminHandler struct {
+	guard   *service.LoginGuard
+	auth    *service.AuthService
+	apiKeys *service.APIKeyService
+	audit   *service.AuditLog
+}
+
+type roleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user organizer admin"`
+}
+
+type unlockRequest struct {
+	Email string `json:"email" binding:"required,email,max=254"`
+}
+
+type apiKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	UserID    uuid.UUID  `json:"userId" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,unique,dive,apiscope"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type apiKeyResponse struct {
+	Key    string        `json:"key"`
+	APIKey domain.APIKey `json:"apiKey"`
+}
+
+func NewAdminHandler(guard *service.LoginGuard, auth *service.AuthService, apiKeys *service.APIKeyService, audit *service.AuditLog) *AdminHandler {
+	return &AdminHandler{guard: guard, auth: auth, apiKeys: apiKeys, audit: audit}
+}
+
+func (h *AdminHandler) LoginAttempts(c *gin.Context) {
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			writeInvalidQuery(c, "limit")
+			return
+		}
+		limit = value
+	}
+
+	attempts, err := h.guard.List(c.Request.Context(), c.Query("email"), limit)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": attempts})
+}
+
+func (h *AdminHandler) Unlock(c *gin.Context) {
+	var payload unlockRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	if err := h.guard.Unlock(c.Request.Context(), payload.Email); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SetRole grants or withdraws the organizer and admin roles. The first admin
+// comes from ADMIN_EMAILS at startup.
+func (h *AdminHandler) SetRole(c *gin.Context) {
+	id, ok := parseUUID(c.Param("id"))
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid_id")
+		return
+	}
+	var payload roleRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	user, err := h.auth.SetRole(c.Request.Context(), id, payload.Role)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) APIKeys(c *gin.Context) {
+	keys, err := h.apiKeys.List(c.Request.Context())
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": keys})
+}
+
+func (h *AdminHandler) CreateAPIKey(c *gin.Context) {
+	adminID, ok := getUserID(c)
+	if !ok {
+		writeError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var payload apiKeyRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	raw, key, err := h.apiKeys.Create(c.Request.Context(), adminID, service.APIKeyInput{
+		Name:      payload.Name,
+		UserID:    payload.UserID,
+		Scopes:    payload.Scopes,
+		ExpiresAt: payload.ExpiresAt,
+	})
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiKeyResponse{Key: raw, APIKey: key})
+}
+
+func (h *AdminHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_id")
+		return
+	}
+
+	if err := h.apiKeys.Revoke(c.Request.Context(), id); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminHandler) AuditLog(c *gin.Context) {
+	filter := domain.AuditFilter{
+		EntityType: c.Query("entityType"),
+		EntityID:   c.Query("entityId"),
+	}
+	if raw := c.Query("actorId"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			writeInvalidQuery(c, "actorId")
+			return
+		}
+		filter.ActorID = &id
+	}
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if raw := c.Query(name); raw != "" {
+			value, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				writeInvalidQuery(c, name)
+				return
+			}
+			*target = &value
+		}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			writeInvalidQuery(c, "limit")
+			return
+		}
+		filter.Limit = value
+	}
+
+	entries, err := h.audit.List(c.Request.Context(), filter)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": entries})
+}
@@ -1,8 +1,10 @@
 package httpapi
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -17,13 +19,31 @@ type AuthHandler struct {
 }
 
 type authResponse struct {
-	Token string      `json:"token"`
-	User  domain.User `json:"user"`
+	Token            string      `json:"token"`
+	ExpiresAt        time.Time   `json:"expiresAt"`
+	RefreshToken     string      `json:"refreshToken"`
+	RefreshExpiresAt time.Time   `json:"refreshExpiresAt"`
+	User             domain.User `json:"user"`
 }
 
 type authRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" binding:"required,email,max=254"`
+	Password string `json:"password" binding:"required,max=1024"`
+}
+
+type mfaChallengeResponse struct {
+	MFARequired bool      `json:"mfaRequired"`
+	MFAToken    string    `json:"mfaToken"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+type mfaVerifyRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required,max=2048"`
+	Code     string `json:"code" binding:"required,max=32"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required,max=512"`
 }
 
 func NewAuthHandler(service *service.AuthService) *AuthHandler {
@@ -32,34 +52,86 @@ func NewAuthHandler(service *service.AuthService) *AuthHandler {
 
 func (h *AuthHandler) Register(c *gin.Context) {
 	var payload authRequest
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		writeError(c, http.StatusBadRequest, "invalid payload")
+	if !bindJSON(c, &payload) {
 		return
 	}
 
-	user, token, err := h.service.Register(c.Request.Context(), payload.Email, payload.Password)
+	user, tokens, err := h.service.Register(c.Request.Context(), payload.Email, payload.Password, clientInfo(c))
 	if err != nil {
-		writeAuthError(c, err)
+		writeServiceError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, authResponse{Token: token, User: user})
+	c.JSON(http.StatusCreated, newAuthResponse(user, tokens))
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
 	var payload authRequest
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		writeError(c, http.StatusBadRequest, "invalid payload")
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	user, tokens, err := h.service.Login(c.Request.Context(), payload.Email, payload.Password, clientInfo(c))
+	writeLoginResult(c, user, tokens, err)
+}
+
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var payload mfaVerifyRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	user, tokens, err := h.service.VerifyMFA(c.Request.Context(), payload.MFAToken, payload.Code, clientInfo(c))
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newAuthResponse(user, tokens))
+}
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var payload refreshRequest
+	if !bindJSON(c, &payload) {
 		return
 	}
 
-	user, token, err := h.service.Login(c.Request.Context(), payload.Email, payload.Password)
+	user, tokens, err := h.service.Refresh(c.Request.Context(), payload.RefreshToken, clientInfo(c))
 	if err != nil {
-		writeAuthError(c, err)
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newAuthResponse(user, tokens))
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var payload refreshRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	if err := h.service.Logout(c.Request.Context(), payload.RefreshToken); err != nil {
+		writeServiceError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, authResponse{Token: token, User: user})
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		writeError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.service.LogoutAll(c.Request.Context(), userID); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
 func (h *AuthHandler) Profile(c *gin.Context) {
@@ -72,15 +144,42 @@ func (h *AuthHandler) Profile(c *gin.Context) {
 
 	user, err := h.service.GetUser(c.Request.Context(), id)
 	if err != nil {
-		writeAuthError(c, err)
+		writeServiceError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, user)
 }
 
-func authMiddleware(service *service.AuthService) gin.HandlerFunc {
+// authMiddleware accepts a bearer JWT or, when scope is set, an API key in
+// X-API-Key that grants that scope. Routes without a scope are for people
+// only, so keys cannot reach account or admin endpoints.
+func authMiddleware(service *service.AuthService, apiKeys *service.APIKeyService, scope string) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if raw := c.GetHeader("X-API-Key"); raw != "" {
+			if scope == "" {
+				writeError(c, http.StatusForbidden, "api_key_not_allowed")
+				c.Abort()
+				return
+			}
+			key, err := apiKeys.Authenticate(c.Request.Context(), raw)
+			if err != nil {
+				writeServiceError(c, err)
+				c.Abort()
+				return
+			}
+			if !key.HasScope(scope) {
+				writeErrorDetail(c, http.StatusForbidden, "missing_scope", "this API key needs the "+scope+" scope")
+				c.Abort()
+				return
+			}
+			c.Set("user_id", key.UserID.String())
+			c.Set("api_key_id", key.ID.String())
+			setActor(c, key.UserID, key.ID)
+			c.Next()
+			return
+		}
+
 		header := c.GetHeader("Authorization")
 		token := ""
 		if header != "" {
@@ -98,27 +197,95 @@ func authMiddleware(service *service.AuthService) gin.HandlerFunc {
 			return
 		}
 
-		userID, err := service.ParseToken(token)
+		claims, err := service.Authenticate(c.Request.Context(), token, c.ClientIP())
 		if err != nil {
-			writeAuthError(c, err)
+			writeServiceError(c, err)
 			c.Abort()
 			return
 		}
 
-		c.Set("user_id", userID.String())
+		c.Set("user_id", claims.UserID.String())
+		c.Set("session_id", claims.SessionID.String())
+		setActor(c, claims.UserID, uuid.Nil)
 		c.Next()
 	}
 }
 
-func writeAuthError(c *gin.Context, err error) {
-	switch {
-	case err == repository.ErrUnauthorized:
-		writeError(c, http.StatusUnauthorized, "unauthorized")
-	case err == repository.ErrConflict:
-		writeError(c, http.StatusConflict, "conflict")
-	case err == repository.ErrNotFound:
-		writeError(c, http.StatusNotFound, "not found")
-	default:
-		writeError(c, http.StatusInternalServerError, "internal error")
+func newAuthResponse(user domain.User, tokens service.TokenPair) authResponse {
+	return authResponse{
+		Token:            tokens.AccessToken,
+		ExpiresAt:        tokens.AccessExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+		User:             user,
+	}
+}
+
+func clientInfo(c *gin.Context) service.ClientInfo {
+	return service.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// writeLoginResult answers a first-factor login: tokens, or an MFA challenge
+// when the account has a second factor.
+func writeLoginResult(c *gin.Context, user domain.User, tokens service.TokenPair, err error) {
+	if err != nil {
+		var challenge *service.MFAChallengeError
+		if errors.As(err, &challenge) {
+			c.JSON(http.StatusOK, mfaChallengeResponse{
+				MFARequired: true,
+				MFAToken:    challenge.Token,
+				ExpiresAt:   challenge.ExpiresAt,
+			})
+			return
+		}
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newAuthResponse(user, tokens))
+}
+
+// requireRole admits users with one of roles and, for roles the MFA policy
+// covers, only once they have enrolled a second factor. API-key callers are
+// held to the key owner's role but not to MFA: a key cannot present a second
+// factor, and it was issued by an admin who had to.
+func requireRole(service *service.AuthService, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := getUserID(c)
+		if !ok {
+			writeError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		user, err := service.GetUser(c.Request.Context(), userID)
+		if err != nil {
+			writeServiceError(c, err)
+			c.Abort()
+			return
+		}
+
+		for _, role := range roles {
+			if user.Role == role {
+				if c.GetString("api_key_id") != "" {
+					c.Next()
+					return
+				}
+				if err := service.EnforceMFA(c.Request.Context(), user); err != nil {
+					if errors.Is(err, repository.ErrForbidden) {
+						writeError(c, http.StatusForbidden, "mfa_enrollment_required")
+					} else {
+						writeServiceError(c, err)
+					}
+					c.Abort()
+					return
+				}
+				c.Next()
+				return
+			}
+		}
+
+		writeError(c, http.StatusForbidden, "forbidden")
+		c.Abort()
 	}
 }
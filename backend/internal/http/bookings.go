@@ -1,6 +1,7 @@
 package httpapi
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -15,8 +16,8 @@ type BookingHandler struct {
 }
 
 type bookingRequest struct {
-	EventID uuid.UUID `json:"eventId"`
-	Seats   []string  `json:"seats"`
+	EventID uuid.UUID `json:"eventId" binding:"required"`
+	Seats   []string  `json:"seats" binding:"required,min=1,max=50,unique,dive,required,max=16"`
 }
 
 func NewBookingHandler(service *service.BookingService) *BookingHandler {
@@ -47,8 +48,7 @@ func (h *BookingHandler) Create(c *gin.Context) {
 	}
 
 	var payload bookingRequest
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		writeError(c, http.StatusBadRequest, "invalid payload")
+	if !bindJSON(c, &payload) {
 		return
 	}
 
@@ -70,13 +70,13 @@ func (h *BookingHandler) Cancel(c *gin.Context) {
 
 	bookingID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		writeError(c, http.StatusBadRequest, "invalid id")
+		writeError(c, http.StatusBadRequest, "invalid_id")
 		return
 	}
 
 	if err := h.service.Cancel(c.Request.Context(), bookingID, userID); err != nil {
 		if err == repository.ErrNotFound {
-			writeError(c, http.StatusNotFound, "not found")
+			writeError(c, http.StatusNotFound, "not_found")
 			return
 		}
 		writeServiceError(c, err)
@@ -89,7 +89,7 @@ func (h *BookingHandler) Cancel(c *gin.Context) {
 func (h *BookingHandler) Seats(c *gin.Context) {
 	eventID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		writeError(c, http.StatusBadRequest, "invalid id")
+		writeError(c, http.StatusBadRequest, "invalid_id")
 		return
 	}
 
@@ -113,3 +113,50 @@ func getUserID(c *gin.Context) (uuid.UUID, bool) {
 	}
 	return id, true
 }
+
+type bookingPatch struct {
+	Metadata json.RawMessage `json:"metadata"`
+}
+
+// Patch applies a JSON Merge Patch to the booking. Only metadata can be
+// changed; seats and status go through Create and Cancel.
+func (h *BookingHandler) Patch(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		writeError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_id")
+		return
+	}
+	patch, ok := readMergePatch(c)
+	if !ok {
+		return
+	}
+
+	current, err := h.service.Get(c.Request.Context(), bookingID, userID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	var payload bookingPatch
+	if err := applyMergePatch(bookingPatch{Metadata: current.Metadata}, patch, &payload); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_payload")
+		return
+	}
+	if payload.Metadata == nil {
+		payload.Metadata = json.RawMessage("{}")
+	}
+
+	updated, err := h.service.UpdateMetadata(c.Request.Context(), bookingID, userID, payload.Metadata)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
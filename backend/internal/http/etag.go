@@ -0,0 +1,55 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// writeETag sets the ETag for version and answers 304 when it matches
+// If-None-Match, returning true if the response has been written.
+func writeETag(c *gin.Context, version int) bool {
+	etag := versionETag(version)
+	c.Header("ETag", etag)
+
+	for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion reads the version a PUT expects to replace. "*" matches any
+// version and yields zero. It writes the error response itself when the
+// header is missing (428) or cannot match any version (412).
+func ifMatchVersion(c *gin.Context) (int, bool) {
+	raw := strings.TrimSpace(c.GetHeader("If-Match"))
+	if raw == "" {
+		writeError(c, http.StatusPreconditionRequired, "if_match_required")
+		return 0, false
+	}
+	if raw == "*" {
+		return 0, true
+	}
+
+	unquoted, err := strconv.Unquote(raw)
+	if err != nil {
+		writeError(c, http.StatusPreconditionFailed, "precondition_failed")
+		return 0, false
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version <= 0 {
+		writeError(c, http.StatusPreconditionFailed, "precondition_failed")
+		return 0, false
+	}
+	return version, true
+}
@@ -0,0 +1,148 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/service"
+)
+
+type HallHandler struct {
+	service *service.HallService
+}
+
+type hallPayload struct {
+	Name     string           `json:"name" binding:"required,max=200"`
+	Capacity int              `json:"capacity" binding:"min=0,max=100000"`
+	Layout   []seatRowPayload `json:"layout" binding:"max=200,unique=Label,dive"`
+}
+
+type seatRowPayload struct {
+	Label string `json:"label" binding:"required,max=8"`
+	Seats int    `json:"seats" binding:"min=1,max=500"`
+}
+
+func NewHallHandler(service *service.HallService) *HallHandler {
+	return &HallHandler{service: service}
+}
+
+func (h *HallHandler) List(c *gin.Context) {
+	venueID, ok := parseUUID(c.Param("id"))
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid_id")
+		return
+	}
+
+	halls, err := h.service.List(c.Request.Context(), venueID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": halls})
+}
+
+func (h *HallHandler) Get(c *gin.Context) {
+	venueID, hallID, ok := parseHallParams(c)
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid_id")
+		return
+	}
+
+	hall, err := h.service.Get(c.Request.Context(), venueID, hallID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hall)
+}
+
+func (h *HallHandler) Create(c *gin.Context) {
+	venueID, ok := parseUUID(c.Param("id"))
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid_id")
+		return
+	}
+
+	var payload hallPayload
+	if !bindJSON(c, &payload) {
+		return
+	}
+	hall := parseHallPayload(payload)
+	hall.VenueID = venueID
+
+	created, err := h.service.Create(c.Request.Context(), hall)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *HallHandler) Update(c *gin.Context) {
+	venueID, hallID, ok := parseHallParams(c)
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid_id")
+		return
+	}
+
+	var payload hallPayload
+	if !bindJSON(c, &payload) {
+		return
+	}
+	hall := parseHallPayload(payload)
+	hall.ID = hallID
+	hall.VenueID = venueID
+
+	updated, err := h.service.Update(c.Request.Context(), hall)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *HallHandler) Delete(c *gin.Context) {
+	venueID, hallID, ok := parseHallParams(c)
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid_id")
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), venueID, hallID); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func parseHallParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	venueID, ok := parseUUID(c.Param("id"))
+	if !ok {
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	hallID, ok := parseUUID(c.Param("hallId"))
+	if !ok {
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	return venueID, hallID, true
+}
+
+func parseHallPayload(payload hallPayload) domain.Hall {
+	layout := make([]domain.SeatRow, 0, len(payload.Layout))
+	for _, row := range payload.Layout {
+		layout = append(layout, domain.SeatRow{Label: row.Label, Seats: row.Seats})
+	}
+	return domain.Hall{
+		Name:     payload.Name,
+		Capacity: payload.Capacity,
+		Layout:   layout,
+	}
+}
@@ -1,8 +1,9 @@
 package httpapi
 
 import (
-	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -37,7 +38,14 @@ func NewCategoryHandler(service *service.CategoryService) *CategoryHandler {
 	return &CategoryHandler{service: service}
 }
 
+const defaultNearRadiusKm = 10
+
 func (h *EventHandler) List(c *gin.Context) {
+	if near := c.Query("near"); near != "" {
+		h.listNear(c, near)
+		return
+	}
+
 	events, err := h.service.List(c.Request.Context())
 	if err != nil {
 		writeServiceError(c, err)
@@ -47,10 +55,35 @@ func (h *EventHandler) List(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"items": events})
 }
 
+func (h *EventHandler) listNear(c *gin.Context, near string) {
+	lat, lng, ok := parseCoordinates(near)
+	if !ok {
+		writeInvalidQuery(c, "near")
+		return
+	}
+	radiusKm := float64(defaultNearRadiusKm)
+	if raw := c.Query("radiusKm"); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			writeInvalidQuery(c, "radiusKm")
+			return
+		}
+		radiusKm = value
+	}
+
+	events, err := h.service.ListNear(c.Request.Context(), lat, lng, radiusKm)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": events})
+}
+
 func (h *EventHandler) Get(c *gin.Context) {
 	id, ok := parseUUID(c.Param("id"))
 	if !ok {
-		writeError(c, http.StatusBadRequest, "invalid id")
+		writeError(c, http.StatusBadRequest, "invalid_id")
 		return
 	}
 
@@ -60,30 +93,36 @@ func (h *EventHandler) Get(c *gin.Context) {
 		return
 	}
 
+	if writeETag(c, event.Version) {
+		return
+	}
 	c.JSON(http.StatusOK, event)
 }
 
 type eventPayload struct {
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	StartAt     string    `json:"startAt"`
-	EndAt       string    `json:"endAt"`
-	VenueID     uuid.UUID `json:"venueId"`
+	Title       string    `json:"title" binding:"required,max=200"`
+	Description string    `json:"description" binding:"required,max=5000"`
+	StartAt     string    `json:"startAt" binding:"required,eventtime"`
+	EndAt       string    `json:"endAt" binding:"required,eventtime,after=StartAt"`
+	VenueID     uuid.UUID `json:"venueId" binding:"required"`
+	HallID      uuid.UUID `json:"hallId"`
 	Published   bool      `json:"published"`
 }
 
 func (h *EventHandler) Create(c *gin.Context) {
 	var payload eventPayload
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		writeError(c, http.StatusBadRequest, "invalid payload")
+	if !bindJSON(c, &payload) {
 		return
 	}
 
-	event, ok := parseEventPayload(payload)
-	if !ok {
-		writeError(c, http.StatusBadRequest, "invalid payload")
+	event := parseEventPayload(payload)
+	startAt, endAt, err := h.service.ParseTimes(c.Request.Context(), event.VenueID, payload.StartAt, payload.EndAt)
+	if err != nil {
+		writeServiceError(c, err)
 		return
 	}
+	event.StartAt = startAt
+	event.EndAt = endAt
 
 	created, err := h.service.Create(c.Request.Context(), event)
 	if err != nil {
@@ -97,22 +136,79 @@ func (h *EventHandler) Create(c *gin.Context) {
 func (h *EventHandler) Update(c *gin.Context) {
 	id, ok := parseUUID(c.Param("id"))
 	if !ok {
-		writeError(c, http.StatusBadRequest, "invalid id")
+		writeError(c, http.StatusBadRequest, "invalid_id")
+		return
+	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
 		return
 	}
 
 	var payload eventPayload
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		writeError(c, http.StatusBadRequest, "invalid payload")
+	if !bindJSON(c, &payload) {
 		return
 	}
 
-	event, ok := parseEventPayload(payload)
+	h.update(c, id, version, payload)
+}
+
+// Patch applies a JSON Merge Patch to the event's editable fields, e.g.
+// {"published": true}, and saves the result like Update.
+func (h *EventHandler) Patch(c *gin.Context) {
+	id, ok := parseUUID(c.Param("id"))
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid_id")
+		return
+	}
+	version, ok := ifMatchVersion(c)
 	if !ok {
-		writeError(c, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	patch, ok := readMergePatch(c)
+	if !ok {
+		return
+	}
+
+	current, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	if version == 0 {
+		version = current.Version
+	}
+
+	var payload eventPayload
+	if err := applyMergePatch(eventPayload{
+		Title:       current.Title,
+		Description: current.Description,
+		StartAt:     current.StartAt.Format(time.RFC3339),
+		EndAt:       current.EndAt.Format(time.RFC3339),
+		VenueID:     current.VenueID,
+		HallID:      current.HallID,
+		Published:   current.Published,
+	}, patch, &payload); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_payload")
+		return
+	}
+	if !validatePayload(c, &payload) {
+		return
+	}
+
+	h.update(c, id, version, payload)
+}
+
+func (h *EventHandler) update(c *gin.Context, id uuid.UUID, version int, payload eventPayload) {
+	event := parseEventPayload(payload)
+	startAt, endAt, err := h.service.ParseTimes(c.Request.Context(), event.VenueID, payload.StartAt, payload.EndAt)
+	if err != nil {
+		writeServiceError(c, err)
 		return
 	}
 	event.ID = id
+	event.Version = version
+	event.StartAt = startAt
+	event.EndAt = endAt
 
 	updated, err := h.service.Update(c.Request.Context(), event)
 	if err != nil {
@@ -120,13 +216,14 @@ func (h *EventHandler) Update(c *gin.Context) {
 		return
 	}
 
+	writeETag(c, updated.Version)
 	c.JSON(http.StatusOK, updated)
 }
 
 func (h *EventHandler) Delete(c *gin.Context) {
 	id, ok := parseUUID(c.Param("id"))
 	if !ok {
-		writeError(c, http.StatusBadRequest, "invalid id")
+		writeError(c, http.StatusBadRequest, "invalid_id")
 		return
 	}
 
@@ -151,7 +248,7 @@ func (h *VenueHandler) List(c *gin.Context) {
 func (h *VenueHandler) Get(c *gin.Context) {
 	id, ok := parseUUID(c.Param("id"))
 	if !ok {
-		writeError(c, http.StatusBadRequest, "invalid id")
+		writeError(c, http.StatusBadRequest, "invalid_id")
 		return
 	}
 
@@ -161,24 +258,30 @@ func (h *VenueHandler) Get(c *gin.Context) {
 		return
 	}
 
+	if writeETag(c, venue.Version) {
+		return
+	}
 	c.JSON(http.StatusOK, venue)
 }
 
 type venuePayload struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Address string `json:"address"`
+	ID        string   `json:"id" binding:"omitempty,uuid"`
+	Name      string   `json:"name" binding:"required,max=200"`
+	Address   string   `json:"address" binding:"required,max=300"`
+	City      string   `json:"city" binding:"max=100"`
+	Latitude  *float64 `json:"latitude" binding:"required_with=Longitude,omitempty,latitude"`
+	Longitude *float64 `json:"longitude" binding:"required_with=Latitude,omitempty,longitude"`
+	Timezone  string   `json:"timezone" binding:"omitempty,timezone"`
 }
 
 func (h *VenueHandler) Create(c *gin.Context) {
 	var payload venuePayload
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		writeError(c, http.StatusBadRequest, "invalid payload")
+	if !bindJSON(c, &payload) {
 		return
 	}
-	venue, ok := parseVenuePayload(payload)
-	if !ok {
-		writeError(c, http.StatusBadRequest, "invalid payload")
+	venue, err := parseVenuePayload(payload)
+	if err != nil {
+		writeServiceError(c, err)
 		return
 	}
 
@@ -194,20 +297,74 @@ func (h *VenueHandler) Create(c *gin.Context) {
 func (h *VenueHandler) Update(c *gin.Context) {
 	id, ok := parseUUID(c.Param("id"))
 	if !ok {
-		writeError(c, http.StatusBadRequest, "invalid id")
+		writeError(c, http.StatusBadRequest, "invalid_id")
+		return
+	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
 		return
 	}
 	var payload venuePayload
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		writeError(c, http.StatusBadRequest, "invalid payload")
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	h.update(c, id, version, payload)
+}
+
+// Patch applies a JSON Merge Patch to the venue's editable fields and saves
+// the result like Update.
+func (h *VenueHandler) Patch(c *gin.Context) {
+	id, ok := parseUUID(c.Param("id"))
+	if !ok {
+		writeError(c, http.StatusBadRequest, "invalid_id")
 		return
 	}
-	venue, ok := parseVenuePayload(payload)
+	version, ok := ifMatchVersion(c)
 	if !ok {
-		writeError(c, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	patch, ok := readMergePatch(c)
+	if !ok {
+		return
+	}
+
+	current, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	if version == 0 {
+		version = current.Version
+	}
+
+	var payload venuePayload
+	if err := applyMergePatch(venuePayload{
+		Name:      current.Name,
+		Address:   current.Address,
+		City:      current.City,
+		Latitude:  current.Latitude,
+		Longitude: current.Longitude,
+		Timezone:  current.Timezone,
+	}, patch, &payload); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_payload")
+		return
+	}
+	if !validatePayload(c, &payload) {
+		return
+	}
+
+	h.update(c, id, version, payload)
+}
+
+func (h *VenueHandler) update(c *gin.Context, id uuid.UUID, version int, payload venuePayload) {
+	venue, err := parseVenuePayload(payload)
+	if err != nil {
+		writeServiceError(c, err)
 		return
 	}
 	venue.ID = id
+	venue.Version = version
 
 	updated, err := h.service.Update(c.Request.Context(), venue)
 	if err != nil {
@@ -215,13 +372,14 @@ func (h *VenueHandler) Update(c *gin.Context) {
 		return
 	}
 
+	writeETag(c, updated.Version)
 	c.JSON(http.StatusOK, updated)
 }
 
 func (h *VenueHandler) Delete(c *gin.Context) {
 	id, ok := parseUUID(c.Param("id"))
 	if !ok {
-		writeError(c, http.StatusBadRequest, "invalid id")
+		writeError(c, http.StatusBadRequest, "invalid_id")
 		return
 	}
 	if err := h.service.Delete(c.Request.Context(), id); err != nil {
@@ -244,7 +402,7 @@ func (h *CategoryHandler) List(c *gin.Context) {
 func (h *CategoryHandler) Get(c *gin.Context) {
 	id, ok := parseUUID(c.Param("id"))
 	if !ok {
-		writeError(c, http.StatusBadRequest, "invalid id")
+		writeError(c, http.StatusBadRequest, "invalid_id")
 		return
 	}
 
@@ -265,63 +423,47 @@ func parseUUID(raw string) (uuid.UUID, bool) {
 	return id, true
 }
 
-func parseEventPayload(payload eventPayload) (domain.Event, bool) {
-	if payload.Title == "" || payload.Description == "" || payload.VenueID == uuid.Nil {
-		return domain.Event{}, false
+func parseCoordinates(raw string) (float64, float64, bool) {
+	latRaw, lngRaw, ok := strings.Cut(raw, ",")
+	if !ok {
+		return 0, 0, false
 	}
-
-	startAt, err := time.Parse(time.RFC3339, payload.StartAt)
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latRaw), 64)
 	if err != nil {
-		return domain.Event{}, false
+		return 0, 0, false
 	}
-	endAt, err := time.Parse(time.RFC3339, payload.EndAt)
+	lng, err := strconv.ParseFloat(strings.TrimSpace(lngRaw), 64)
 	if err != nil {
-		return domain.Event{}, false
+		return 0, 0, false
 	}
+	return lat, lng, true
+}
 
+func parseEventPayload(payload eventPayload) domain.Event {
 	return domain.Event{
 		Title:       payload.Title,
 		Description: payload.Description,
-		StartAt:     startAt.UTC(),
-		EndAt:       endAt.UTC(),
 		VenueID:     payload.VenueID,
+		HallID:      payload.HallID,
 		Published:   payload.Published,
-	}, true
+	}
 }
 
-func parseVenuePayload(payload venuePayload) (domain.Venue, bool) {
-	if payload.Name == "" || payload.Address == "" {
-		return domain.Venue{}, false
-	}
+func parseVenuePayload(payload venuePayload) (domain.Venue, error) {
 	venue := domain.Venue{
-		Name:    payload.Name,
-		Address: payload.Address,
+		Name:      payload.Name,
+		Address:   payload.Address,
+		City:      payload.City,
+		Latitude:  payload.Latitude,
+		Longitude: payload.Longitude,
+		Timezone:  payload.Timezone,
 	}
 	if payload.ID != "" {
 		id, err := uuid.Parse(payload.ID)
 		if err != nil {
-			return domain.Venue{}, false
+			return domain.Venue{}, repository.Invalid("id", "invalid", "expected a UUID")
 		}
 		venue.ID = id
 	}
-	return venue, true
-}
-
-func writeError(c *gin.Context, status int, message string) {
-	c.JSON(status, gin.H{"error": message})
-}
-
-func writeServiceError(c *gin.Context, err error) {
-	switch {
-	case errors.Is(err, repository.ErrNotFound):
-		writeError(c, http.StatusNotFound, "not found")
-	case errors.Is(err, repository.ErrConflict):
-		writeError(c, http.StatusConflict, "conflict")
-	case errors.Is(err, repository.ErrUnauthorized):
-		writeError(c, http.StatusUnauthorized, "unauthorized")
-	case errors.Is(err, repository.ErrInvalid):
-		writeError(c, http.StatusBadRequest, "invalid")
-	default:
-		writeError(c, http.StatusInternalServerError, "internal error")
-	}
+	return venue, nil
 }
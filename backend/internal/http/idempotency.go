@@ -0,0 +1,108 @@
+package httpapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"islamdiplom/internal/logging"
+	"islamdiplom/internal/service"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotentBody    = 1 << 20
+)
+
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *capturingWriter) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// idempotencyMiddleware replays the stored response when a request is
+// retried with the same Idempotency-Key. Keys are scoped to clientSubject,
+// so it must run after any auth middleware on the route. Requests without
+// the header are handled as usual.
+func idempotencyMiddleware(idempotency *service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentBody+1))
+		if err != nil || len(body) > maxIdempotentBody {
+			writeError(c, http.StatusRequestEntityTooLarge, "payload_too_large")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+		subject := clientSubject(c)
+		ctx := c.Request.Context()
+		record, replay, err := idempotency.Begin(ctx, subject, key, fingerprint)
+		if err != nil {
+			if errors.Is(err, service.ErrIdempotencyKeyReused) {
+				writeErrorDetail(c, http.StatusUnprocessableEntity, "idempotency_key_reused", "the key was first used with a different request")
+			} else {
+				writeServiceError(c, err)
+			}
+			c.Abort()
+			return
+		}
+		if replay {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.StatusCode, record.ContentType, record.Body)
+			c.Abort()
+			return
+		}
+
+		// Server errors and panics are not stored so the client can retry.
+		release := true
+		defer func() {
+			if release {
+				if err := idempotency.Release(ctx, subject, key); err != nil {
+					logging.FromContext(ctx).Warn("idempotency key release failed", zap.Error(err))
+				}
+			}
+		}()
+
+		writer := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		// The handler has done its work, so the key stays reserved even when
+		// the response cannot be stored: a retry must not run it again until
+		// the lease runs out.
+		release = false
+		if err := idempotency.Complete(ctx, subject, key, status, writer.Header().Get("Content-Type"), writer.body.Bytes()); err != nil {
+			logging.FromContext(ctx).Error("idempotency response not stored", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository/memory"
+	"islamdiplom/internal/service"
+)
+
+const (
+	testIdempotencyPath = "/bookings"
+	testClientIP        = "192.0.2.1"
+)
+
+// idempotencyFixture routes testIdempotencyPath through the middleware to
+// handler, which counts how often it runs.
+type idempotencyFixture struct {
+	repo    *memory.IdempotencyRepository
+	router  *gin.Engine
+	mu      sync.Mutex
+	calls   int
+	handler func(c *gin.Context, call int)
+}
+
+func newIdempotencyFixture(t *testing.T, handler func(c *gin.Context, call int)) *idempotencyFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	f := &idempotencyFixture{repo: memory.NewIdempotencyRepository(), handler: handler}
+	f.router = gin.New()
+	f.router.POST(testIdempotencyPath, idempotencyMiddleware(service.NewIdempotencyService(f.repo, time.Hour)), func(c *gin.Context) {
+		f.mu.Lock()
+		f.calls++
+		call := f.calls
+		f.mu.Unlock()
+		f.handler(c, call)
+	})
+	return f
+}
+
+func (f *idempotencyFixture) post(key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, testIdempotencyPath, strings.NewReader(body))
+	req.RemoteAddr = testClientIP + ":1234"
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func (f *idempotencyFixture) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func created(c *gin.Context, call int) {
+	body, _ := io.ReadAll(c.Request.Body)
+	c.JSON(http.StatusCreated, gin.H{"call": call, "echo": string(body)})
+}
+
+func problemCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var p problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode problem %q: %v", rec.Body.String(), err)
+	}
+	return p.Code
+}
+
+func TestIdempotencyReplaysSameKey(t *testing.T) {
+	f := newIdempotencyFixture(t, created)
+
+	first := f.post("key-1", `{"seats":["A1"]}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want 201", first.Code)
+	}
+	second := f.post("key-1", `{"seats":["A1"]}`)
+	if second.Code != http.StatusCreated {
+		t.Fatalf("replay status = %d, want 201", second.Code)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatal("replay is missing the Idempotent-Replayed header")
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Fatalf("replay body = %s, want %s", second.Body, first.Body)
+	}
+	if got := second.Header().Get("Content-Type"); got != first.Header().Get("Content-Type") {
+		t.Fatalf("replay Content-Type = %q, want %q", got, first.Header().Get("Content-Type"))
+	}
+	if f.callCount() != 1 {
+		t.Fatalf("handler ran %d times, want 1", f.callCount())
+	}
+
+	// Without the header every request runs.
+	f.post("", `{"seats":["A1"]}`)
+	if f.callCount() != 2 {
+		t.Fatalf("handler ran %d times, want 2", f.callCount())
+	}
+}
+
+func TestIdempotencyRejectsKeyWithDifferentBody(t *testing.T) {
+	f := newIdempotencyFixture(t, created)
+
+	f.post("key-1", `{"seats":["A1"]}`)
+	rec := f.post("key-1", `{"seats":["B2"]}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rec.Code)
+	}
+	if code := problemCode(t, rec); code != "idempotency_key_reused" {
+		t.Fatalf("code = %q, want idempotency_key_reused", code)
+	}
+	if f.callCount() != 1 {
+		t.Fatalf("handler ran %d times, want 1", f.callCount())
+	}
+}
+
+func TestIdempotencyConflictWhileInFlight(t *testing.T) {
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	f := newIdempotencyFixture(t, func(c *gin.Context, call int) {
+		if call == 1 {
+			close(started)
+			<-finish
+		}
+		created(c, call)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- f.post("key-1", `{}`) }()
+	<-started
+
+	rec := f.post("key-1", `{}`)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status while in flight = %d, want 409", rec.Code)
+	}
+
+	close(finish)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want 201", first.Code)
+	}
+	if rec := f.post("key-1", `{}`); rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("status after completion = %d, want a replay", rec.Code)
+	}
+}
+
+func TestIdempotencyReleasesKeyAfterServerError(t *testing.T) {
+	f := newIdempotencyFixture(t, func(c *gin.Context, call int) {
+		if call == 1 {
+			writeError(c, http.StatusServiceUnavailable, "unavailable")
+			return
+		}
+		created(c, call)
+	})
+
+	if rec := f.post("key-1", `{}`); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("first status = %d, want 503", rec.Code)
+	}
+	rec := f.post("key-1", `{}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("retry status = %d, replayed = %q, want a fresh 201", rec.Code, rec.Header().Get("Idempotent-Replayed"))
+	}
+	if f.callCount() != 2 {
+		t.Fatalf("handler ran %d times, want 2", f.callCount())
+	}
+}
+
+func TestIdempotencyStoresClientErrors(t *testing.T) {
+	f := newIdempotencyFixture(t, func(c *gin.Context, call int) {
+		writeError(c, http.StatusBadRequest, "bad_request")
+	})
+
+	f.post("key-1", `{}`)
+	rec := f.post("key-1", `{}`)
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("retry status = %d, want a replayed 400", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != problemContentType {
+		t.Fatalf("replay Content-Type = %q, want %q", got, problemContentType)
+	}
+}
+
+func TestIdempotencyTakesOverExpiredLease(t *testing.T) {
+	f := newIdempotencyFixture(t, created)
+	body := `{"seats":["A1"]}`
+
+	// A request that died without finishing or releasing its key, e.g. when
+	// the process crashed.
+	reserve := func(lockedUntil time.Time) {
+		t.Helper()
+		now := time.Now().UTC()
+		_, ok, err := f.repo.Reserve(context.Background(), domain.IdempotencyRecord{
+			Subject:     "ip:" + testClientIP,
+			Key:         "key-1",
+			Fingerprint: testFingerprint(http.MethodPost, testIdempotencyPath, body),
+			CreatedAt:   now.Add(-2 * time.Minute),
+			ExpiresAt:   now.Add(time.Hour),
+			LockedUntil: lockedUntil,
+		})
+		if err != nil || !ok {
+			t.Fatalf("Reserve = %v, %v", ok, err)
+		}
+	}
+
+	reserve(time.Now().Add(time.Minute))
+	if rec := f.post("key-1", body); rec.Code != http.StatusConflict {
+		t.Fatalf("status under a live lease = %d, want 409", rec.Code)
+	}
+	if rec := f.post("key-1", `{"seats":["B2"]}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status for another body = %d, want 422", rec.Code)
+	}
+
+	f.repo.Release(context.Background(), "ip:"+testClientIP, "key-1")
+	reserve(time.Now().Add(-time.Second))
+	if rec := f.post("key-1", body); rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("status after the lease ran out = %d, want a fresh 201", rec.Code)
+	}
+	if rec := f.post("key-1", body); rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("status after takeover = %d, want a replay", rec.Code)
+	}
+	if f.callCount() != 1 {
+		t.Fatalf("handler ran %d times, want 1", f.callCount())
+	}
+}
+
+// testFingerprint mirrors how idempotencyMiddleware identifies a request.
+func testFingerprint(method, path, body string) string {
+	sum := sha256.Sum256([]byte(method + " " + path + "\n" + body))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,20 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"islamdiplom/internal/service"
+)
+
+type jwksResponse struct {
+	Keys []service.JWK `json:"keys"`
+}
+
+func jwksHandler(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwksResponse{Keys: authService.JWKS()})
+	}
+}
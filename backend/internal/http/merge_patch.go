@@ -0,0 +1,90 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	maxMergePatchBody     = 1 << 20
+)
+
+// readMergePatch reads a JSON Merge Patch (RFC 7396) body. Plain
+// application/json is accepted as well since many clients cannot set a
+// custom content type. It writes the error response itself on failure.
+func readMergePatch(c *gin.Context) (json.RawMessage, bool) {
+	if raw := c.GetHeader("Content-Type"); raw != "" {
+		mediaType, _, err := mime.ParseMediaType(raw)
+		if err != nil || (mediaType != mergePatchContentType && mediaType != "application/json") {
+			writeError(c, http.StatusUnsupportedMediaType, "unsupported_content_type")
+			return nil, false
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxMergePatchBody+1))
+	if err != nil || len(body) > maxMergePatchBody || !json.Valid(body) {
+		writeError(c, http.StatusBadRequest, "invalid_payload")
+		return nil, false
+	}
+	return body, true
+}
+
+// applyMergePatch merges patch into the JSON form of current and decodes the
+// result into out. Members the document does not have are rejected.
+func applyMergePatch(current any, patch json.RawMessage, out any) error {
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	target, err := decodeJSON(raw)
+	if err != nil {
+		return err
+	}
+	changes, err := decodeJSON(patch)
+	if err != nil {
+		return err
+	}
+
+	merged, err := json.Marshal(mergeValue(target, changes))
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(out)
+}
+
+func mergeValue(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any, len(patchObject))
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergeValue(targetObject[key], value)
+	}
+	return targetObject
+}
+
+func decodeJSON(raw []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
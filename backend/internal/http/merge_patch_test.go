@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMergeValueRFC7396(t *testing.T) {
+	// The examples from RFC 7396 Appendix A.
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{target: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{target: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{target: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{target: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{target: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{target: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{target: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		{target: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{target: `{"a":"foo"}`, patch: `null`, want: `null`},
+		{target: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{target: `{"e":null}`, patch: `{"a":1}`, want: `{"a":1,"e":null}`},
+		{target: `[1,2]`, patch: `{"a":"b","c":null}`, want: `{"a":"b"}`},
+		{target: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target+" "+tt.patch, func(t *testing.T) {
+			target, err := decodeJSON([]byte(tt.target))
+			if err != nil {
+				t.Fatalf("decode target: %v", err)
+			}
+			patch, err := decodeJSON([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("decode patch: %v", err)
+			}
+			got, err := json.Marshal(mergeValue(target, patch))
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("merged = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+type testPatchDocument struct {
+	Title    string            `json:"title"`
+	Capacity *int              `json:"capacity"`
+	Address  testPatchAddress  `json:"address"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+type testPatchAddress struct {
+	City   string `json:"city"`
+	Street string `json:"street"`
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	capacity := 120
+	current := testPatchDocument{
+		Title:    "Lecture",
+		Capacity: &capacity,
+		Address:  testPatchAddress{City: "Kazan", Street: "Baumana 1"},
+		Metadata: map[string]string{"room": "A", "floor": "2"},
+	}
+
+	tests := []struct {
+		name    string
+		patch   string
+		check   func(t *testing.T, got testPatchDocument)
+		wantErr bool
+	}{
+		{
+			name:  "empty patch keeps the document",
+			patch: `{}`,
+			check: func(t *testing.T, got testPatchDocument) {
+				if got.Title != "Lecture" || got.Capacity == nil || *got.Capacity != 120 || got.Address.City != "Kazan" {
+					t.Fatalf("document changed: %+v", got)
+				}
+			},
+		},
+		{
+			name:  "replaces a member",
+			patch: `{"title":"Seminar"}`,
+			check: func(t *testing.T, got testPatchDocument) {
+				if got.Title != "Seminar" || got.Capacity == nil || *got.Capacity != 120 {
+					t.Fatalf("got %+v", got)
+				}
+			},
+		},
+		{
+			name:  "null deletes a member",
+			patch: `{"capacity":null}`,
+			check: func(t *testing.T, got testPatchDocument) {
+				if got.Capacity != nil {
+					t.Fatalf("capacity = %d, want nil", *got.Capacity)
+				}
+			},
+		},
+		{
+			name:  "nested objects merge",
+			patch: `{"address":{"street":"Pushkina 5"},"metadata":{"floor":null,"wing":"east"}}`,
+			check: func(t *testing.T, got testPatchDocument) {
+				if got.Address != (testPatchAddress{City: "Kazan", Street: "Pushkina 5"}) {
+					t.Fatalf("address = %+v", got.Address)
+				}
+				if len(got.Metadata) != 2 || got.Metadata["room"] != "A" || got.Metadata["wing"] != "east" {
+					t.Fatalf("metadata = %v", got.Metadata)
+				}
+			},
+		},
+		{name: "unknown member", patch: `{"organizer":"someone"}`, wantErr: true},
+		{name: "unknown nested member", patch: `{"address":{"zip":"420111"}}`, wantErr: true},
+		{name: "non-object patch replaces the document", patch: `["Seminar"]`, wantErr: true},
+		{name: "wrong type", patch: `{"capacity":"many"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got testPatchDocument
+			err := applyMergePatch(current, json.RawMessage(tt.patch), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("applyMergePatch = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyMergePatch: %v", err)
+			}
+			tt.check(t, got)
+		})
+	}
+
+	if current.Title != "Lecture" || len(current.Metadata) != 2 {
+		t.Fatalf("the current document was modified: %+v", current)
+	}
+}
@@ -0,0 +1,114 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"islamdiplom/internal/service"
+)
+
+type MFAHandler struct {
+	service *service.MFAService
+}
+
+type mfaStatusResponse struct {
+	Enabled  bool `json:"enabled"`
+	Required bool `json:"required"`
+}
+
+type mfaEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+type mfaDisableRequest struct {
+	// Password is left out by accounts created through an identity provider.
+	Password string `json:"password" binding:"max=1024"`
+	Code     string `json:"code" binding:"required,max=32"`
+}
+
+type mfaRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+func NewMFAHandler(service *service.MFAService) *MFAHandler {
+	return &MFAHandler{service: service}
+}
+
+func (h *MFAHandler) Status(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		writeError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	status, err := h.service.Status(c.Request.Context(), userID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mfaStatusResponse{Enabled: status.Enabled, Required: status.Required})
+}
+
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		writeError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	enrollment, err := h.service.Enroll(c.Request.Context(), userID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mfaEnrollResponse{Secret: enrollment.Secret, URI: enrollment.URI})
+}
+
+func (h *MFAHandler) Confirm(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		writeError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var payload mfaCodeRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	codes, err := h.service.Confirm(c.Request.Context(), userID, payload.Code)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mfaRecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		writeError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var payload mfaDisableRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	if err := h.service.Disable(c.Request.Context(), userID, payload.Password, payload.Code); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,18 +1,229 @@
 package httpapi
 
-import "github.com/gin-gonic/gin"
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"islamdiplom/internal/logging"
+	"islamdiplom/internal/service"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type SecurityHeadersConfig struct {
+	HSTSMaxAge            time.Duration
+	ContentSecurityPolicy string
+}
+
+// RouterOptions carries the HTTP-level settings that are not backed by a
+// service. A nil Logger discards request logs. X-Forwarded-For is honoured
+// only from TrustedProxies (IPs or CIDRs); with none, the client IP is the
+// peer address, so callers cannot spoof it for login throttling or rate
+// limits.
+type RouterOptions struct {
+	Logger          *zap.Logger
+	TrustedProxies  []string
+	CORS            CORSConfig
+	SecurityHeaders SecurityHeadersConfig
+}
+
+// corsMiddleware answers only for listed origins. "*" allows any origin and
+// is only accepted without credentials; main refuses to start otherwise.
+func corsMiddleware(cfg CORSConfig) gin.HandlerFunc {
+	allowAny := false
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAny = true
+		}
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
 
-func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Auth-Token")
+		header := c.Writer.Header()
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		if !allowAny {
+			header.Add("Vary", "Origin")
+		}
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin != "" && (allowAny || allowed[origin]) {
+			if allowAny {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if preflight {
+				header.Set("Access-Control-Allow-Methods", methods)
+				header.Set("Access-Control-Allow-Headers", headers)
+				if cfg.MaxAge > 0 {
+					header.Set("Access-Control-Max-Age", maxAge)
+				}
+			} else if exposed != "" {
+				header.Set("Access-Control-Expose-Headers", exposed)
+			}
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
 
 		c.Next()
 	}
 }
+
+// securityHeadersMiddleware sets headers for every response. The API only
+// serves JSON, so the default policy forbids loading or framing anything.
+func securityHeadersMiddleware(cfg SecurityHeadersConfig) gin.HandlerFunc {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds())) + "; includeSubDomains"
+	}
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "no-referrer")
+		if cfg.ContentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+		}
+		if hsts != "" {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		c.Next()
+	}
+}
+
+// requestIDMiddleware keeps a caller-supplied request ID or assigns one, so
+// log lines and audit entries can be traced back to the request that produced
+// them. The request context gets a logger that tags every entry with the ID.
+func requestIDMiddleware(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.NewString()
+		}
+		c.Writer.Header().Set(requestIDHeader, requestID)
+
+		ctx := service.WithRequestID(c.Request.Context(), requestID)
+		ctx = logging.WithLogger(ctx, logger.With(zap.String("requestId", requestID)))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// accessLogMiddleware writes one entry per request once it has been served.
+// The route is the template, e.g. /api/events/:id, so entries group well.
+func accessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		fields := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("route", c.FullPath()),
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes", c.Writer.Size()),
+			zap.String("clientIp", c.ClientIP()),
+		}
+		// The request logger already carries requestId and, once the caller
+		// is authenticated, userId.
+		logger := logging.FromContext(c.Request.Context())
+		switch {
+		case status >= http.StatusInternalServerError:
+			logger.Error("request served", fields...)
+		case status >= http.StatusBadRequest:
+			logger.Warn("request served", fields...)
+		default:
+			logger.Info("request served", fields...)
+		}
+	}
+}
+
+// recoveryMiddleware turns a panic into a 500 problem response and logs it
+// with the stack. A client that hung up gets no response at all.
+func recoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if err, ok := recovered.(error); ok && isBrokenPipe(err) {
+				logging.FromContext(c.Request.Context()).Warn("client connection lost", zap.Error(err))
+				c.Abort()
+				return
+			}
+			logging.FromContext(c.Request.Context()).Error("panic recovered",
+				zap.Any("panic", recovered),
+				zap.Stack("stack"),
+			)
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			writeError(c, http.StatusInternalServerError, "internal_error")
+			c.Abort()
+		}()
+		c.Next()
+	}
+}
+
+func isBrokenPipe(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if !errors.As(opErr, &syscallErr) {
+		return false
+	}
+	return errors.Is(syscallErr, syscall.EPIPE) || errors.Is(syscallErr, syscall.ECONNRESET)
+}
+
+// setActor records who is calling and adds them to the request's logger.
+func setActor(c *gin.Context, userID, apiKeyID uuid.UUID) {
+	actor := service.Actor{UserID: userID, APIKeyID: apiKeyID}
+	ctx := service.WithActor(c.Request.Context(), actor)
+	fields := []zap.Field{zap.String("userId", userID.String())}
+	if apiKeyID != uuid.Nil {
+		fields = append(fields, zap.String("apiKeyId", apiKeyID.String()))
+	}
+	c.Request = c.Request.WithContext(logging.With(ctx, fields...))
+}
@@ -0,0 +1,54 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"islamdiplom/internal/service"
+)
+
+type OIDCHandler struct {
+	service *service.OIDCService
+}
+
+type oidcProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+type oidcStartResponse struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+}
+
+type oidcCallbackRequest struct {
+	Code  string `json:"code" binding:"required,max=2048"`
+	State string `json:"state" binding:"required,max=512"`
+}
+
+func NewOIDCHandler(service *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{service: service}
+}
+
+func (h *OIDCHandler) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, oidcProvidersResponse{Providers: h.service.Providers()})
+}
+
+func (h *OIDCHandler) Start(c *gin.Context) {
+	authorizationURL, err := h.service.Start(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, oidcStartResponse{AuthorizationURL: authorizationURL})
+}
+
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var payload oidcCallbackRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	user, tokens, err := h.service.Callback(c.Request.Context(), c.Param("provider"), payload.Code, payload.State, clientInfo(c))
+	writeLoginResult(c, user, tokens, err)
+}
@@ -0,0 +1,93 @@
+package httpapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"islamdiplom/internal/service"
+)
+
+type PasswordHandler struct {
+	service *service.PasswordService
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email,max=254"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required,max=512"`
+	Password string `json:"password" binding:"required,max=1024"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required,max=1024"`
+	NewPassword     string `json:"newPassword" binding:"required,max=1024"`
+}
+
+type tokenResponse struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+func NewPasswordHandler(service *service.PasswordService) *PasswordHandler {
+	return &PasswordHandler{service: service}
+}
+
+func (h *PasswordHandler) Forgot(c *gin.Context) {
+	var payload forgotPasswordRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	if err := h.service.Forgot(c.Request.Context(), payload.Email); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+func (h *PasswordHandler) Reset(c *gin.Context) {
+	var payload resetPasswordRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	if err := h.service.Reset(c.Request.Context(), payload.Token, payload.Password); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *PasswordHandler) Change(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		writeError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var payload changePasswordRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	tokens, err := h.service.Change(c.Request.Context(), userID, payload.CurrentPassword, payload.NewPassword, clientInfo(c))
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResponse{
+		Token:            tokens.AccessToken,
+		ExpiresAt:        tokens.AccessExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+	})
+}
@@ -0,0 +1,126 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"islamdiplom/internal/logging"
+	"islamdiplom/internal/repository"
+	"islamdiplom/internal/service"
+)
+
+const problemContentType = "application/problem+json"
+
+// problem is an RFC 7807 response body. Code is a stable snake_case
+// identifier clients can switch on; the optional members carry the details
+// of typed service errors.
+type problem struct {
+	Type      string                  `json:"type"`
+	Title     string                  `json:"title"`
+	Status    int                     `json:"status"`
+	Code      string                  `json:"code"`
+	Detail    string                  `json:"detail,omitempty"`
+	Instance  string                  `json:"instance,omitempty"`
+	RequestID string                  `json:"requestId,omitempty"`
+	Errors    []repository.FieldError `json:"errors,omitempty"`
+	EventID   *uuid.UUID              `json:"eventId,omitempty"`
+	Seats     []string                `json:"seats,omitempty"`
+	Available *int                    `json:"available,omitempty"`
+}
+
+func writeProblem(c *gin.Context, p problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	p.Instance = c.Request.URL.Path
+	p.RequestID = service.RequestIDFromContext(c.Request.Context())
+
+	c.Header("Content-Type", problemContentType)
+	c.JSON(p.Status, p)
+}
+
+func writeError(c *gin.Context, status int, code string) {
+	writeProblem(c, problem{Status: status, Code: code})
+}
+
+func writeErrorDetail(c *gin.Context, status int, code, detail string) {
+	writeProblem(c, problem{Status: status, Code: code, Detail: detail})
+}
+
+// writeInvalidQuery reports a malformed query parameter in the same shape as
+// a failed payload validation.
+func writeInvalidQuery(c *gin.Context, name string) {
+	writeProblem(c, problem{
+		Status: http.StatusBadRequest,
+		Code:   "validation_failed",
+		Errors: []repository.FieldError{{Field: name, Code: "invalid", Message: "malformed query parameter"}},
+	})
+}
+
+func writeServiceError(c *gin.Context, err error) {
+	var (
+		overlap    *repository.OverlapError
+		seatsTaken *repository.SeatsTakenError
+		capacity   *repository.CapacityError
+		validation *repository.ValidationError
+		tooMany    *repository.TooManyRequestsError
+	)
+	switch {
+	case errors.As(err, &overlap):
+		writeProblem(c, problem{
+			Status:  http.StatusConflict,
+			Code:    "event_overlap",
+			Detail:  "the hall is already booked for an overlapping event",
+			EventID: &overlap.EventID,
+		})
+	case errors.As(err, &seatsTaken):
+		writeProblem(c, problem{
+			Status: http.StatusConflict,
+			Code:   "seats_taken",
+			Detail: "some of the requested seats are already booked",
+			Seats:  seatsTaken.Seats,
+		})
+	case errors.As(err, &capacity):
+		writeProblem(c, problem{
+			Status:    http.StatusConflict,
+			Code:      "capacity_exceeded",
+			Detail:    "not enough free seats left",
+			Available: &capacity.Available,
+		})
+	case errors.As(err, &validation):
+		writeProblem(c, problem{
+			Status: http.StatusBadRequest,
+			Code:   "validation_failed",
+			Errors: validation.Fields,
+		})
+	case errors.Is(err, repository.ErrNotFound):
+		writeError(c, http.StatusNotFound, "not_found")
+	case errors.Is(err, repository.ErrConflict):
+		writeError(c, http.StatusConflict, "conflict")
+	case errors.Is(err, repository.ErrUnauthorized):
+		writeError(c, http.StatusUnauthorized, "unauthorized")
+	case errors.Is(err, repository.ErrInvalid):
+		writeError(c, http.StatusBadRequest, "invalid")
+	case errors.Is(err, repository.ErrForbidden):
+		writeError(c, http.StatusForbidden, "forbidden")
+	case errors.As(err, &tooMany):
+		if tooMany.RetryAfter > 0 {
+			c.Header("Retry-After", seconds(tooMany.RetryAfter))
+		}
+		writeError(c, http.StatusTooManyRequests, "too_many_requests")
+	case errors.Is(err, repository.ErrTooManyRequests):
+		writeError(c, http.StatusTooManyRequests, "too_many_requests")
+	case errors.Is(err, repository.ErrPreconditionFailed):
+		writeError(c, http.StatusPreconditionFailed, "precondition_failed")
+	default:
+		logging.FromContext(c.Request.Context()).Error("request failed", zap.Error(err))
+		writeError(c, http.StatusInternalServerError, "internal_error")
+	}
+}
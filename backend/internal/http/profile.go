@@ -0,0 +1,90 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"islamdiplom/internal/service"
+)
+
+type ProfileHandler struct {
+	service *service.ProfileService
+}
+
+type profileRequest struct {
+	Name              string `json:"name" binding:"max=100"`
+	Phone             string `json:"phone" binding:"max=32"`
+	PreferredLanguage string `json:"preferredLanguage" binding:"max=16"`
+}
+
+type deleteAccountRequest struct {
+	// Password is left out by accounts created through an identity provider.
+	Password string `json:"password" binding:"max=1024"`
+}
+
+func NewProfileHandler(service *service.ProfileService) *ProfileHandler {
+	return &ProfileHandler{service: service}
+}
+
+func (h *ProfileHandler) Update(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		writeError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var payload profileRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	user, err := h.service.Update(c.Request.Context(), userID, service.ProfileInput{
+		Name:              payload.Name,
+		Phone:             payload.Phone,
+		PreferredLanguage: payload.PreferredLanguage,
+	})
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *ProfileHandler) Export(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		writeError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	export, err := h.service.Export(c.Request.Context(), userID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="account-export.json"`)
+	c.JSON(http.StatusOK, export)
+}
+
+func (h *ProfileHandler) Delete(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		writeError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var payload deleteAccountRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), userID, payload.Password); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,52 @@
+package httpapi
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"islamdiplom/internal/service"
+)
+
+// rateLimitMiddleware limits each clientSubject separately, so it must run
+// after any auth middleware on the route.
+func rateLimitMiddleware(limiter *service.RateLimiter, policyName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy, decision, ok := limiter.Take(c.Request.Context(), policyName, clientSubject(c))
+		if !ok {
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("RateLimit-Policy", strconv.Itoa(policy.Limit)+";w="+seconds(policy.Window))
+		header.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		header.Set("RateLimit-Reset", seconds(decision.Reset))
+
+		if !decision.Allowed {
+			header.Set("Retry-After", seconds(decision.RetryAfter))
+			writeError(c, http.StatusTooManyRequests, "too_many_requests")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// clientSubject identifies the caller: the authenticated user when auth
+// middleware has run, otherwise the client IP. ClientIP only honours
+// X-Forwarded-For from TRUSTED_PROXIES, so clients cannot pick their bucket.
+func clientSubject(c *gin.Context) string {
+	if userID, ok := getUserID(c); ok {
+		return "user:" + userID.String()
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -1,66 +1,157 @@
 package httpapi
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 
+	"islamdiplom/internal/domain"
 	"islamdiplom/internal/service"
 )
 
 func NewRouter(
 	eventService *service.EventService,
 	venueService *service.VenueService,
+	hallService *service.HallService,
 	categoryService *service.CategoryService,
 	authService *service.AuthService,
+	mfaService *service.MFAService,
+	oidcService *service.OIDCService,
+	profileService *service.ProfileService,
+	passwordService *service.PasswordService,
+	verificationService *service.VerificationService,
+	loginGuard *service.LoginGuard,
+	apiKeyService *service.APIKeyService,
+	auditLog *service.AuditLog,
+	rateLimiter *service.RateLimiter,
+	idempotencyService *service.IdempotencyService,
 	bookingService *service.BookingService,
-) http.Handler {
+	searchService *service.SearchService,
+	options RouterOptions,
+) (http.Handler, error) {
+	logger := options.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
 	router := gin.New()
+	if err := router.SetTrustedProxies(options.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
 	router.Use(
-		gin.Logger(),
-		gin.Recovery(),
-		corsMiddleware(),
+		requestIDMiddleware(logger),
+		accessLogMiddleware(),
+		recoveryMiddleware(),
+		securityHeadersMiddleware(options.SecurityHeaders),
+		corsMiddleware(options.CORS),
 	)
 
 	eventHandler := NewEventHandler(eventService)
 	venueHandler := NewVenueHandler(venueService)
+	hallHandler := NewHallHandler(hallService)
 	categoryHandler := NewCategoryHandler(categoryService)
 	authHandler := NewAuthHandler(authService)
+	mfaHandler := NewMFAHandler(mfaService)
+	oidcHandler := NewOIDCHandler(oidcService)
+	profileHandler := NewProfileHandler(profileService)
+	passwordHandler := NewPasswordHandler(passwordService)
+	verificationHandler := NewVerificationHandler(verificationService)
+	adminHandler := NewAdminHandler(loginGuard, authService, apiKeyService, auditLog)
 	bookingHandler := NewBookingHandler(bookingService)
+	searchHandler := NewSearchHandler(searchService)
+
+	userAuth := authMiddleware(authService, apiKeyService, "")
+	eventsAuth := authMiddleware(authService, apiKeyService, domain.ScopeEventsWrite)
+	venuesAuth := authMiddleware(authService, apiKeyService, domain.ScopeVenuesWrite)
+	bookingsReadAuth := authMiddleware(authService, apiKeyService, domain.ScopeBookingsRead)
+	bookingsWriteAuth := authMiddleware(authService, apiKeyService, domain.ScopeBookingsWrite)
+	editor := requireRole(authService, domain.RoleOrganizer, domain.RoleAdmin)
+
+	authLimit := rateLimitMiddleware(rateLimiter, domain.RateLimitAuth)
+	bookingLimit := rateLimitMiddleware(rateLimiter, domain.RateLimitBookings)
+	catalogueLimit := rateLimitMiddleware(rateLimiter, domain.RateLimitCatalogue)
+	// Token refreshes and provider lookups happen on every page load, so they
+	// get their own budget instead of eating into the credential bucket.
+	sessionLimit := rateLimitMiddleware(rateLimiter, domain.RateLimitSession)
+	idempotent := idempotencyMiddleware(idempotencyService)
 
 	router.GET("/health", healthHandler)
+	router.GET("/.well-known/jwks.json", jwksHandler(authService))
 
 	api := router.Group("/api")
 	{
-		api.GET("/events", eventHandler.List)
-		api.GET("/events/:id", eventHandler.Get)
-		api.GET("/events/:id/occupied-seats", bookingHandler.Seats)
-		api.GET("/venues", venueHandler.List)
-		api.GET("/venues/:id", venueHandler.Get)
-		api.POST("/venues", authMiddleware(authService), venueHandler.Create)
-		api.PUT("/venues/:id", authMiddleware(authService), venueHandler.Update)
-		api.DELETE("/venues/:id", authMiddleware(authService), venueHandler.Delete)
-		api.GET("/categories", categoryHandler.List)
-		api.GET("/categories/:id", categoryHandler.Get)
-
-		api.POST("/auth/register", authHandler.Register)
-		api.POST("/auth/login", authHandler.Login)
-
-		api.GET("/profile", authMiddleware(authService), authHandler.Profile)
-
-		api.POST("/events", authMiddleware(authService), eventHandler.Create)
-		api.PUT("/events/:id", authMiddleware(authService), eventHandler.Update)
-		api.DELETE("/events/:id", authMiddleware(authService), eventHandler.Delete)
-
-		bookings := api.Group("/bookings", authMiddleware(authService))
+		api.GET("/events", catalogueLimit, eventHandler.List)
+		api.GET("/events/:id", catalogueLimit, eventHandler.Get)
+		api.GET("/events/:id/occupied-seats", catalogueLimit, bookingHandler.Seats)
+		api.GET("/venues", catalogueLimit, venueHandler.List)
+		api.GET("/venues/:id", catalogueLimit, venueHandler.Get)
+		api.POST("/venues", venuesAuth, editor, idempotent, venueHandler.Create)
+		api.PUT("/venues/:id", venuesAuth, editor, venueHandler.Update)
+		api.PATCH("/venues/:id", venuesAuth, editor, venueHandler.Patch)
+		api.DELETE("/venues/:id", venuesAuth, editor, venueHandler.Delete)
+		api.GET("/venues/:id/halls", catalogueLimit, hallHandler.List)
+		api.GET("/venues/:id/halls/:hallId", catalogueLimit, hallHandler.Get)
+		api.POST("/venues/:id/halls", venuesAuth, editor, idempotent, hallHandler.Create)
+		api.PUT("/venues/:id/halls/:hallId", venuesAuth, editor, hallHandler.Update)
+		api.DELETE("/venues/:id/halls/:hallId", venuesAuth, editor, hallHandler.Delete)
+		api.GET("/categories", catalogueLimit, categoryHandler.List)
+		api.GET("/categories/:id", catalogueLimit, categoryHandler.Get)
+		api.GET("/search", catalogueLimit, searchHandler.Search)
+
+		api.POST("/auth/register", authLimit, authHandler.Register)
+		api.POST("/auth/login", authLimit, authHandler.Login)
+		api.POST("/auth/mfa/verify", authLimit, authHandler.VerifyMFA)
+		api.GET("/auth/oidc/providers", sessionLimit, oidcHandler.Providers)
+		api.POST("/auth/oidc/:provider/start", authLimit, oidcHandler.Start)
+		api.POST("/auth/oidc/:provider/callback", authLimit, oidcHandler.Callback)
+		api.POST("/auth/refresh", sessionLimit, authHandler.Refresh)
+		api.POST("/auth/logout", sessionLimit, authHandler.Logout)
+		api.POST("/auth/logout-all", userAuth, authLimit, authHandler.LogoutAll)
+		api.POST("/auth/password/forgot", authLimit, passwordHandler.Forgot)
+		api.POST("/auth/password/reset", authLimit, passwordHandler.Reset)
+		api.POST("/auth/password/change", userAuth, authLimit, passwordHandler.Change)
+		api.POST("/auth/email/verify", authLimit, verificationHandler.Verify)
+		api.POST("/auth/email/resend", userAuth, authLimit, verificationHandler.Resend)
+
+		api.GET("/profile", userAuth, authHandler.Profile)
+		api.PUT("/profile", userAuth, profileHandler.Update)
+		api.DELETE("/profile", userAuth, profileHandler.Delete)
+		api.GET("/profile/export", userAuth, profileHandler.Export)
+		api.GET("/profile/sessions", userAuth, authHandler.Sessions)
+		api.DELETE("/profile/sessions/:id", userAuth, authHandler.RevokeSession)
+		api.GET("/profile/mfa", userAuth, mfaHandler.Status)
+		api.POST("/profile/mfa/enroll", userAuth, mfaHandler.Enroll)
+		api.POST("/profile/mfa/confirm", userAuth, mfaHandler.Confirm)
+		api.POST("/profile/mfa/disable", userAuth, mfaHandler.Disable)
+
+		api.POST("/events", eventsAuth, editor, idempotent, eventHandler.Create)
+		api.PUT("/events/:id", eventsAuth, editor, eventHandler.Update)
+		api.PATCH("/events/:id", eventsAuth, editor, eventHandler.Patch)
+		api.DELETE("/events/:id", eventsAuth, editor, eventHandler.Delete)
+
+		admin := api.Group("/admin", userAuth, requireRole(authService, domain.RoleAdmin))
+		{
+			admin.GET("/login-attempts", adminHandler.LoginAttempts)
+			admin.POST("/users/unlock", adminHandler.Unlock)
+			admin.PUT("/users/:id/role", adminHandler.SetRole)
+			admin.GET("/api-keys", adminHandler.APIKeys)
+			admin.POST("/api-keys", adminHandler.CreateAPIKey)
+			admin.DELETE("/api-keys/:id", adminHandler.RevokeAPIKey)
+			admin.GET("/audit-log", adminHandler.AuditLog)
+		}
+
+		bookings := api.Group("/bookings")
 		{
-			bookings.GET("", bookingHandler.List)
-			bookings.POST("", bookingHandler.Create)
-			bookings.DELETE("/:id", bookingHandler.Cancel)
+			bookings.GET("", bookingsReadAuth, bookingHandler.List)
+			bookings.POST("", bookingsWriteAuth, bookingLimit, idempotent, bookingHandler.Create)
+			bookings.PATCH("/:id", bookingsWriteAuth, bookingHandler.Patch)
+			bookings.DELETE("/:id", bookingsWriteAuth, bookingHandler.Cancel)
 		}
 	}
 
-	return router
+	return router, nil
 }
 
 func healthHandler(c *gin.Context) {
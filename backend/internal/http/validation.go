@@ -0,0 +1,161 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+	"islamdiplom/internal/service"
+)
+
+// Request DTOs declare their rules in `binding` tags, which gin checks while
+// binding. The custom rules below cover what the stock validator cannot:
+//
+//	eventtime    RFC 3339 or venue wall-clock time, as the event service reads it
+//	after=Field  a later event time than Field when both use the same notation
+//	apiscope     one of domain.APIKeyScopes
+func init() {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	engine.RegisterValidation("eventtime", func(fl validator.FieldLevel) bool {
+		_, err := service.ParseEventTime(fl.Field().String(), time.UTC)
+		return err == nil
+	})
+	engine.RegisterValidation("after", func(fl validator.FieldLevel) bool {
+		other := fl.Parent().FieldByName(fl.Param())
+		if !other.IsValid() || other.Kind() != reflect.String {
+			return false
+		}
+		start, end := other.String(), fl.Field().String()
+		if isAbsoluteTime(start) != isAbsoluteTime(end) {
+			return true
+		}
+		startAt, err := service.ParseEventTime(start, time.UTC)
+		if err != nil {
+			return true
+		}
+		endAt, err := service.ParseEventTime(end, time.UTC)
+		if err != nil {
+			return true
+		}
+		return endAt.After(startAt)
+	})
+	engine.RegisterValidation("apiscope", func(fl validator.FieldLevel) bool {
+		scope := fl.Field().String()
+		for _, known := range domain.APIKeyScopes {
+			if scope == known {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func isAbsoluteTime(raw string) bool {
+	_, err := time.Parse(time.RFC3339, raw)
+	return err == nil
+}
+
+// bindJSON decodes the body into payload and reports every rule it breaks at
+// once. It writes the response itself when it returns false.
+func bindJSON(c *gin.Context, payload any) bool {
+	err := c.ShouldBindJSON(payload)
+	if err == nil {
+		return true
+	}
+	writeBindingError(c, err)
+	return false
+}
+
+// validatePayload checks a payload that was not bound from the body
+// directly, e.g. the result of a merge patch.
+func validatePayload(c *gin.Context, payload any) bool {
+	err := binding.Validator.ValidateStruct(payload)
+	if err == nil {
+		return true
+	}
+	writeBindingError(c, err)
+	return false
+}
+
+func writeBindingError(c *gin.Context, err error) {
+	var violations validator.ValidationErrors
+	if !errors.As(err, &violations) {
+		writeError(c, http.StatusBadRequest, "invalid_payload")
+		return
+	}
+	fields := make([]repository.FieldError, 0, len(violations))
+	for _, violation := range violations {
+		fields = append(fields, fieldError(violation))
+	}
+	writeServiceError(c, &repository.ValidationError{Fields: fields})
+}
+
+func fieldError(violation validator.FieldError) repository.FieldError {
+	field := violation.Namespace()
+	if _, rest, ok := strings.Cut(field, "."); ok {
+		field = rest
+	}
+	code, message := violation.Tag(), ""
+	kind := violation.Kind()
+	switch violation.Tag() {
+	case "required", "required_with":
+		code = "required"
+	case "email":
+		code, message = "invalid_email", "expected an email address"
+	case "uuid":
+		code, message = "invalid", "expected a UUID"
+	case "latitude", "longitude":
+		code = "out_of_range"
+	case "timezone":
+		code, message = "unknown", "expected an IANA time zone name"
+	case "eventtime":
+		code, message = "invalid_time", "expected RFC 3339 or venue wall-clock time"
+	case "after":
+		code, message = "before_start", field+" must be after "+lowerFirst(violation.Param())
+	case "apiscope":
+		code, message = "unknown_scope", "expected one of "+strings.Join(domain.APIKeyScopes, ", ")
+	case "unique":
+		code, message = "duplicate", "values must not repeat"
+	case "max":
+		code, message = sizeCode(kind, "too_long", "too_many", "too_large"), "at most "+violation.Param()
+	case "min":
+		code, message = sizeCode(kind, "too_short", "too_few", "too_small"), "at least "+violation.Param()
+	}
+	return repository.FieldError{Field: field, Code: code, Message: message}
+}
+
+func sizeCode(kind reflect.Kind, text, items, number string) string {
+	switch kind {
+	case reflect.String:
+		return text
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return items
+	default:
+		return number
+	}
+}
+
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
@@ -0,0 +1,219 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"islamdiplom/internal/repository"
+)
+
+func TestBindJSONReportsEveryViolation(t *testing.T) {
+	long := func(n int) string { return `"` + strings.Repeat("a", n) + `"` }
+
+	tests := []struct {
+		name    string
+		payload any
+		body    string
+		want    []string
+	}{
+		{
+			name:    "authRequest",
+			payload: &authRequest{},
+			body:    `{"email":"not-an-email","password":""}`,
+			want:    []string{"email invalid_email", "password required"},
+		},
+		{
+			name:    "mfaVerifyRequest",
+			payload: &mfaVerifyRequest{},
+			body:    `{"code":` + long(33) + `}`,
+			want:    []string{"mfaToken required", "code too_long"},
+		},
+		{
+			name:    "refreshRequest",
+			payload: &refreshRequest{},
+			body:    `{}`,
+			want:    []string{"refreshToken required"},
+		},
+		{
+			name:    "roleRequest",
+			payload: &roleRequest{},
+			body:    `{"role":"owner"}`,
+			want:    []string{"role oneof"},
+		},
+		{
+			name:    "unlockRequest",
+			payload: &unlockRequest{},
+			body:    `{"email":""}`,
+			want:    []string{"email required"},
+		},
+		{
+			name:    "apiKeyRequest",
+			payload: &apiKeyRequest{},
+			body:    `{"name":` + long(101) + `,"scopes":["events:write","events:delete"]}`,
+			want:    []string{"name too_long", "userId required", "scopes[1] unknown_scope"},
+		},
+		{
+			name:    "apiKeyRequest with repeated scopes",
+			payload: &apiKeyRequest{},
+			body:    `{"name":"partner","userId":"6f1c1f9e-8d57-4a3e-9d7b-0d6a4c1b2f10","scopes":["events:write","events:write"]}`,
+			want:    []string{"scopes duplicate"},
+		},
+		{
+			name:    "bookingRequest",
+			payload: &bookingRequest{},
+			body:    `{"seats":["A1","",` + long(17) + `]}`,
+			want:    []string{"eventId required", "seats[1] required", "seats[2] too_long"},
+		},
+		{
+			name:    "hallPayload",
+			payload: &hallPayload{},
+			body:    `{"name":"","capacity":-1,"layout":[{"label":"A","seats":0},{"label":"","seats":10}]}`,
+			want:    []string{"name required", "capacity too_small", "layout[0].seats too_small", "layout[1].label required"},
+		},
+		{
+			name:    "eventPayload",
+			payload: &eventPayload{},
+			body:    `{"title":` + long(201) + `,"startAt":"tomorrow","endAt":"2024-05-01 25:00","venueId":"6f1c1f9e-8d57-4a3e-9d7b-0d6a4c1b2f10"}`,
+			want:    []string{"title too_long", "description required", "startAt invalid_time", "endAt invalid_time"},
+		},
+		{
+			name:    "eventPayload ending before it starts",
+			payload: &eventPayload{},
+			body:    `{"title":"Lecture","description":"d","startAt":"2024-05-01T12:00","endAt":"2024-05-01T10:00","venueId":"6f1c1f9e-8d57-4a3e-9d7b-0d6a4c1b2f10"}`,
+			want:    []string{"endAt before_start"},
+		},
+		{
+			name:    "venuePayload",
+			payload: &venuePayload{},
+			body:    `{"id":"42","name":"Hall","address":` + long(301) + `,"latitude":91,"timezone":"Mars/Olympus"}`,
+			want:    []string{"id invalid", "address too_long", "latitude out_of_range", "longitude required", "timezone unknown"},
+		},
+		{
+			name:    "mfaCodeRequest",
+			payload: &mfaCodeRequest{},
+			body:    `{}`,
+			want:    []string{"code required"},
+		},
+		{
+			name:    "mfaDisableRequest",
+			payload: &mfaDisableRequest{},
+			body:    `{"password":` + long(1025) + `}`,
+			want:    []string{"password too_long", "code required"},
+		},
+		{
+			name:    "oidcCallbackRequest",
+			payload: &oidcCallbackRequest{},
+			body:    `{"code":"c"}`,
+			want:    []string{"state required"},
+		},
+		{
+			name:    "forgotPasswordRequest",
+			payload: &forgotPasswordRequest{},
+			body:    `{"email":"user"}`,
+			want:    []string{"email invalid_email"},
+		},
+		{
+			name:    "resetPasswordRequest",
+			payload: &resetPasswordRequest{},
+			body:    `{"token":` + long(513) + `}`,
+			want:    []string{"token too_long", "password required"},
+		},
+		{
+			name:    "changePasswordRequest",
+			payload: &changePasswordRequest{},
+			body:    `{"newPassword":` + long(1025) + `}`,
+			want:    []string{"currentPassword required", "newPassword too_long"},
+		},
+		{
+			name:    "profileRequest",
+			payload: &profileRequest{},
+			body:    `{"name":` + long(101) + `,"phone":` + long(33) + `,"preferredLanguage":` + long(17) + `}`,
+			want:    []string{"name too_long", "phone too_long", "preferredLanguage too_long"},
+		},
+		{
+			name:    "deleteAccountRequest",
+			payload: &deleteAccountRequest{},
+			body:    `{"password":` + long(1025) + `}`,
+			want:    []string{"password too_long"},
+		},
+		{
+			name:    "verifyEmailRequest",
+			payload: &verifyEmailRequest{},
+			body:    `{}`,
+			want:    []string{"token required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := bindTestPayload(t, tt.payload, tt.body)
+			if ok {
+				t.Fatalf("bindJSON accepted %s", tt.body)
+			}
+			if p.Status != http.StatusBadRequest || p.Code != "validation_failed" {
+				t.Fatalf("problem = %d %s, want 400 validation_failed", p.Status, p.Code)
+			}
+			got := make([]string, 0, len(p.Errors))
+			for _, field := range p.Errors {
+				got = append(got, field.Field+" "+field.Code)
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Fatalf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindJSONMessages(t *testing.T) {
+	p, _ := bindTestPayload(t, &eventPayload{}, `{"title":"Lecture","description":"d","startAt":"2024-05-01T12:00:00Z","endAt":"2024-05-01T10:00:00Z","venueId":"6f1c1f9e-8d57-4a3e-9d7b-0d6a4c1b2f10"}`)
+	want := repository.FieldError{Field: "endAt", Code: "before_start", Message: "endAt must be after startAt"}
+	if len(p.Errors) != 1 || p.Errors[0] != want {
+		t.Fatalf("errors = %+v, want %+v", p.Errors, want)
+	}
+
+	p, _ = bindTestPayload(t, &mfaCodeRequest{}, `{"code":`+`"`+strings.Repeat("1", 33)+`"}`)
+	want = repository.FieldError{Field: "code", Code: "too_long", Message: "at most 32"}
+	if len(p.Errors) != 1 || p.Errors[0] != want {
+		t.Fatalf("errors = %+v, want %+v", p.Errors, want)
+	}
+}
+
+func TestBindJSONAcceptsMixedTimeNotations(t *testing.T) {
+	// An absolute and a wall-clock time can only be ordered once the venue's
+	// zone is known, so the service checks them instead.
+	_, ok := bindTestPayload(t, &eventPayload{}, `{"title":"Lecture","description":"d","startAt":"2024-05-01T12:00:00Z","endAt":"2024-05-01T10:00","venueId":"6f1c1f9e-8d57-4a3e-9d7b-0d6a4c1b2f10"}`)
+	if !ok {
+		t.Fatal("bindJSON rejected an event mixing time notations")
+	}
+}
+
+func TestBindJSONMalformedBody(t *testing.T) {
+	p, ok := bindTestPayload(t, &authRequest{}, `{"email":`)
+	if ok || p.Status != http.StatusBadRequest || p.Code != "invalid_payload" {
+		t.Fatalf("problem = %d %s, want 400 invalid_payload", p.Status, p.Code)
+	}
+}
+
+// bindTestPayload runs bindJSON on body and decodes the problem it wrote.
+func bindTestPayload(t *testing.T, payload any, body string) (problem, bool) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	if bindJSON(c, payload) {
+		return problem{}, true
+	}
+	var p problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode problem %q: %v", rec.Body.String(), err)
+	}
+	return p, false
+}
@@ -0,0 +1,50 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"islamdiplom/internal/service"
+)
+
+type VerificationHandler struct {
+	service *service.VerificationService
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required,max=512"`
+}
+
+func NewVerificationHandler(service *service.VerificationService) *VerificationHandler {
+	return &VerificationHandler{service: service}
+}
+
+func (h *VerificationHandler) Verify(c *gin.Context) {
+	var payload verifyEmailRequest
+	if !bindJSON(c, &payload) {
+		return
+	}
+
+	if err := h.service.Verify(c.Request.Context(), payload.Token); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *VerificationHandler) Resend(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		writeError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.service.Resend(c.Request.Context(), userID); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
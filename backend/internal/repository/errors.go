@@ -1,8 +1,98 @@
 package repository
 
-import "errors"
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 var ErrNotFound = errors.New("not found")
 var ErrConflict = errors.New("conflict")
 var ErrUnauthorized = errors.New("unauthorized")
 var ErrInvalid = errors.New("invalid")
+var ErrForbidden = errors.New("forbidden")
+var ErrTooManyRequests = errors.New("too many requests")
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+type OverlapError struct {
+	EventID uuid.UUID
+}
+
+func (e *OverlapError) Error() string {
+	return "overlaps event " + e.EventID.String()
+}
+
+func (e *OverlapError) Unwrap() error {
+	return ErrConflict
+}
+
+// SeatsTakenError lists requested seats that are already booked.
+type SeatsTakenError struct {
+	Seats []string
+}
+
+func (e *SeatsTakenError) Error() string {
+	return "seats already booked: " + strings.Join(e.Seats, ", ")
+}
+
+func (e *SeatsTakenError) Unwrap() error {
+	return ErrConflict
+}
+
+// CapacityError means a booking would exceed the hall capacity.
+type CapacityError struct {
+	Available int
+}
+
+func (e *CapacityError) Error() string {
+	return "only " + strconv.Itoa(e.Available) + " seats available"
+}
+
+func (e *CapacityError) Unwrap() error {
+	return ErrConflict
+}
+
+// TooManyRequestsError is a lockout that lifts after RetryAfter.
+type TooManyRequestsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyRequestsError) Error() string {
+	return "too many requests, retry after " + e.RetryAfter.String()
+}
+
+func (e *TooManyRequestsError) Unwrap() error {
+	return ErrTooManyRequests
+}
+
+// FieldError explains why one request field was rejected. Code is stable
+// and meant for clients; Message is for people.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Invalid reports a single rejected field.
+func Invalid(field, code, message string) error {
+	return &ValidationError{Fields: []FieldError{{Field: field, Code: code, Message: message}}}
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		parts = append(parts, field.Field+": "+field.Code)
+	}
+	return "invalid " + strings.Join(parts, ", ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalid
+}
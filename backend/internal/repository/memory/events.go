@@ -16,6 +16,7 @@ type EventRepository struct {
 func NewEventRepository() *EventRepository {
 	now := time.Now().UTC()
 	venueID := uuid.New()
+	hallID := uuid.New()
 	return &EventRepository{
 		events: []domain.Event{
 			{
@@ -25,8 +26,10 @@ func NewEventRepository() *EventRepository {
 				StartAt:     now.Add(48 * time.Hour),
 				EndAt:       now.Add(50 * time.Hour),
 				VenueID:     venueID,
+				HallID:      hallID,
 				Published:   true,
 				CreatedAt:   now.Add(-24 * time.Hour),
+				Version:     1,
 				UpdatedAt:   now.Add(-2 * time.Hour),
 			},
 		},
@@ -49,6 +52,7 @@ func (r *EventRepository) Get(_ context.Context, id uuid.UUID) (domain.Event, er
 func (r *EventRepository) Create(_ context.Context, event domain.Event) (domain.Event, error) {
 	now := time.Now().UTC()
 	event.ID = uuid.New()
+	event.Version = 1
 	event.CreatedAt = now
 	event.UpdatedAt = now
 	r.events = append(r.events, event)
@@ -58,6 +62,10 @@ func (r *EventRepository) Create(_ context.Context, event domain.Event) (domain.
 func (r *EventRepository) Update(_ context.Context, event domain.Event) (domain.Event, error) {
 	for i, existing := range r.events {
 		if existing.ID == event.ID {
+			if event.Version != 0 && event.Version != existing.Version {
+				return domain.Event{}, repository.ErrPreconditionFailed
+			}
+			event.Version = existing.Version + 1
 			event.CreatedAt = existing.CreatedAt
 			event.UpdatedAt = time.Now().UTC()
 			r.events[i] = event
@@ -76,3 +84,21 @@ func (r *EventRepository) Delete(_ context.Context, id uuid.UUID) error {
 	}
 	return repository.ErrNotFound
 }
+
+func (r *EventRepository) ListOverlapping(_ context.Context, hallID uuid.UUID, from, to time.Time, excludeID uuid.UUID) ([]domain.Event, error) {
+	var events []domain.Event
+	for _, event := range r.events {
+		if event.HallID != hallID || event.ID == excludeID {
+			continue
+		}
+		if event.StartAt.Before(to) && event.EndAt.After(from) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *EventRepository) ListNear(_ context.Context, _, _, _ float64, _ time.Time) ([]domain.NearbyEvent, error) {
+	// The in-memory store has no venue coordinates to measure against.
+	return nil, nil
+}
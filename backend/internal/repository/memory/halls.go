@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+type HallRepository struct {
+	halls []domain.Hall
+}
+
+func NewHallRepository() *HallRepository {
+	return &HallRepository{}
+}
+
+func (r *HallRepository) ListByVenue(_ context.Context, venueID uuid.UUID) ([]domain.Hall, error) {
+	var halls []domain.Hall
+	for _, hall := range r.halls {
+		if hall.VenueID == venueID {
+			halls = append(halls, hall)
+		}
+	}
+	return halls, nil
+}
+
+func (r *HallRepository) Get(_ context.Context, id uuid.UUID) (domain.Hall, error) {
+	for _, hall := range r.halls {
+		if hall.ID == id {
+			return hall, nil
+		}
+	}
+	return domain.Hall{}, repository.ErrNotFound
+}
+
+func (r *HallRepository) Create(_ context.Context, hall domain.Hall) (domain.Hall, error) {
+	for _, existing := range r.halls {
+		if existing.VenueID == hall.VenueID && existing.Name == hall.Name {
+			return domain.Hall{}, repository.ErrConflict
+		}
+	}
+	now := time.Now().UTC()
+	hall.ID = uuid.New()
+	hall.CreatedAt = now
+	hall.UpdatedAt = now
+	r.halls = append(r.halls, hall)
+	return hall, nil
+}
+
+func (r *HallRepository) Update(_ context.Context, hall domain.Hall) (domain.Hall, error) {
+	for i, existing := range r.halls {
+		if existing.ID == hall.ID {
+			hall.VenueID = existing.VenueID
+			hall.CreatedAt = existing.CreatedAt
+			hall.UpdatedAt = time.Now().UTC()
+			r.halls[i] = hall
+			return hall, nil
+		}
+	}
+	return domain.Hall{}, repository.ErrNotFound
+}
+
+func (r *HallRepository) Delete(_ context.Context, id uuid.UUID) error {
+	for i, existing := range r.halls {
+		if existing.ID == id {
+			r.halls = append(r.halls[:i], r.halls[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"islamdiplom/internal/domain"
+)
+
+type idempotencyKey struct {
+	subject string
+	key     string
+}
+
+type IdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[idempotencyKey]domain.IdempotencyRecord
+}
+
+func NewIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{records: make(map[idempotencyKey]domain.IdempotencyRecord)}
+}
+
+func (r *IdempotencyRepository) Reserve(_ context.Context, record domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := idempotencyKey{subject: record.Subject, key: record.Key}
+	existing, ok := r.records[id]
+	if ok && !existing.ExpiresAt.After(record.CreatedAt) {
+		ok = false
+	}
+	if !ok {
+		r.records[id] = record
+		return record, true, nil
+	}
+
+	// A retry of the same request takes over a reservation whose lease has
+	// run out without a stored response.
+	if !existing.Completed() && !existing.LockedUntil.After(record.CreatedAt) && existing.Fingerprint == record.Fingerprint {
+		existing.CreatedAt = record.CreatedAt
+		existing.ExpiresAt = record.ExpiresAt
+		existing.LockedUntil = record.LockedUntil
+		r.records[id] = existing
+		return existing, true, nil
+	}
+	return existing, false, nil
+}
+
+func (r *IdempotencyRepository) Complete(_ context.Context, subject, key string, statusCode int, contentType string, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := idempotencyKey{subject: subject, key: key}
+	record, ok := r.records[id]
+	if !ok {
+		return nil
+	}
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.Body = append([]byte(nil), body...)
+	r.records[id] = record
+	return nil
+}
+
+func (r *IdempotencyRepository) Release(_ context.Context, subject, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := idempotencyKey{subject: subject, key: key}
+	if record, ok := r.records[id]; ok && !record.Completed() {
+		delete(r.records, id)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepository) Prune(_ context.Context, before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, record := range r.records {
+		if !record.ExpiresAt.After(before) {
+			delete(r.records, id)
+		}
+	}
+	return nil
+}
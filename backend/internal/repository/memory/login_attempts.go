@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"islamdiplom/internal/domain"
+)
+
+type LoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts []domain.LoginAttempt
+}
+
+func NewLoginAttemptRepository() *LoginAttemptRepository {
+	return &LoginAttemptRepository{}
+}
+
+func (r *LoginAttemptRepository) RecordFailure(_ context.Context, attempt domain.LoginAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt.ID = uuid.New()
+	r.attempts = append(r.attempts, attempt)
+	return nil
+}
+
+func (r *LoginAttemptRepository) CountFailures(_ context.Context, email, ip string, since time.Time) (domain.LoginFailures, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var failures domain.LoginFailures
+	for _, attempt := range r.attempts {
+		if attempt.CreatedAt.Before(since) {
+			continue
+		}
+		if attempt.Email == email && attempt.ClearedAt == nil {
+			failures.ByEmail++
+			if failures.OldestByEmail.IsZero() || attempt.CreatedAt.Before(failures.OldestByEmail) {
+				failures.OldestByEmail = attempt.CreatedAt
+			}
+		}
+		if attempt.IP == ip {
+			failures.ByIP++
+			if failures.OldestByIP.IsZero() || attempt.CreatedAt.Before(failures.OldestByIP) {
+				failures.OldestByIP = attempt.CreatedAt
+			}
+		}
+	}
+	return failures, nil
+}
+
+func (r *LoginAttemptRepository) Clear(_ context.Context, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range r.attempts {
+		if r.attempts[i].Email == email && r.attempts[i].ClearedAt == nil {
+			r.attempts[i].ClearedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *LoginAttemptRepository) List(_ context.Context, email string, limit int) ([]domain.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var attempts []domain.LoginAttempt
+	for _, attempt := range r.attempts {
+		if email == "" || attempt.Email == email {
+			attempts = append(attempts, attempt)
+		}
+	}
+	sort.Slice(attempts, func(i, j int) bool {
+		return attempts[i].CreatedAt.After(attempts[j].CreatedAt)
+	})
+	if len(attempts) > limit {
+		attempts = attempts[:limit]
+	}
+	return attempts, nil
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"islamdiplom/internal/domain"
+)
+
+type RateLimitRepository struct {
+	mu      sync.Mutex
+	buckets map[string]domain.RateLimitBucket
+}
+
+func NewRateLimitRepository() *RateLimitRepository {
+	return &RateLimitRepository{buckets: make(map[string]domain.RateLimitBucket)}
+}
+
+func (r *RateLimitRepository) Take(_ context.Context, key string, policy domain.RateLimitPolicy, now time.Time) (domain.RateLimitDecision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bucket, ok := r.buckets[key]
+	if !ok {
+		bucket = domain.FullRateLimitBucket(policy, now)
+	}
+	bucket, decision := bucket.Take(policy, now)
+	r.buckets[key] = bucket
+	return decision, nil
+}
+
+func (r *RateLimitRepository) Prune(_ context.Context, before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, bucket := range r.buckets {
+		if bucket.UpdatedAt.Before(before) {
+			delete(r.buckets, key)
+		}
+	}
+	return nil
+}
@@ -21,7 +21,9 @@ func NewVenueRepository() *VenueRepository {
 				ID:        uuid.New(),
 				Name:      "Городская галерея",
 				Address:   "ул. Центральная, 10",
+				Timezone:  "Asia/Almaty",
 				CreatedAt: now.Add(-48 * time.Hour),
+				Version:   1,
 				UpdatedAt: now.Add(-24 * time.Hour),
 			},
 		},
@@ -46,6 +48,7 @@ func (r *VenueRepository) Create(_ context.Context, venue domain.Venue) (domain.
 	if venue.ID == uuid.Nil {
 		venue.ID = uuid.New()
 	}
+	venue.Version = 1
 	venue.CreatedAt = now
 	venue.UpdatedAt = now
 	r.venues = append(r.venues, venue)
@@ -55,6 +58,10 @@ func (r *VenueRepository) Create(_ context.Context, venue domain.Venue) (domain.
 func (r *VenueRepository) Update(_ context.Context, venue domain.Venue) (domain.Venue, error) {
 	for i, existing := range r.venues {
 		if existing.ID == venue.ID {
+			if venue.Version != 0 && venue.Version != existing.Version {
+				return domain.Venue{}, repository.ErrPreconditionFailed
+			}
+			venue.Version = existing.Version + 1
 			venue.CreatedAt = existing.CreatedAt
 			venue.UpdatedAt = time.Now().UTC()
 			r.venues[i] = venue
@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 
 	"github.com/google/uuid"
 	"islamdiplom/internal/domain"
@@ -19,7 +21,7 @@ func NewBookingRepository(db *sql.DB) *BookingRepository {
 
 func (r *BookingRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Booking, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, user_id, event_id, status, total_price, currency, created_at, updated_at
+		SELECT id, user_id, event_id, status, total_price, currency, metadata, created_at, updated_at
 		FROM bookings
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -31,17 +33,8 @@ func (r *BookingRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([
 
 	var bookings []domain.Booking
 	for rows.Next() {
-		var booking domain.Booking
-		if err := rows.Scan(
-			&booking.ID,
-			&booking.UserID,
-			&booking.EventID,
-			&booking.Status,
-			&booking.TotalPrice,
-			&booking.Currency,
-			&booking.CreatedAt,
-			&booking.UpdatedAt,
-		); err != nil {
+		booking, err := scanBooking(rows)
+		if err != nil {
 			return nil, err
 		}
 		bookings = append(bookings, booking)
@@ -72,28 +65,28 @@ func (r *BookingRepository) Create(ctx context.Context, booking domain.Booking)
 	}
 	defer func() {
 		if err != nil {
-			_ = tx.Rollback()
+			rollback(ctx, tx)
 		}
 	}()
 
+	seats := booking.Seats
+	if err = lockSeats(ctx, tx, booking.EventID, seats); err != nil {
+		return domain.Booking{}, err
+	}
+	metadata := booking.Metadata
+	if len(metadata) == 0 {
+		metadata = json.RawMessage("{}")
+	}
 	row := tx.QueryRowContext(ctx, `
-		INSERT INTO bookings (user_id, event_id, status, total_price, currency)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, user_id, event_id, status, total_price, currency, created_at, updated_at
-	`, booking.UserID, booking.EventID, booking.Status, booking.TotalPrice, booking.Currency)
+		INSERT INTO bookings (user_id, event_id, status, total_price, currency, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, user_id, event_id, status, total_price, currency, metadata, created_at, updated_at
+	`, booking.UserID, booking.EventID, booking.Status, booking.TotalPrice, booking.Currency, []byte(metadata))
 
-	if err = row.Scan(
-		&booking.ID,
-		&booking.UserID,
-		&booking.EventID,
-		&booking.Status,
-		&booking.TotalPrice,
-		&booking.Currency,
-		&booking.CreatedAt,
-		&booking.UpdatedAt,
-	); err != nil {
+	if booking, err = scanBooking(row); err != nil {
 		return domain.Booking{}, err
 	}
+	booking.Seats = seats
 
 	for _, seat := range booking.Seats {
 		if _, err = tx.ExecContext(ctx, `
@@ -111,6 +104,124 @@ func (r *BookingRepository) Create(ctx context.Context, booking domain.Booking)
 	return booking, nil
 }
 
+// lockSeats serializes bookings for one event by locking its row, then
+// repeats the service's seat and capacity checks inside the transaction so
+// two concurrent requests cannot both take the last seats.
+func lockSeats(ctx context.Context, tx *sql.Tx, eventID uuid.UUID, seats []string) error {
+	var capacity int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT h.capacity
+		FROM events e
+		JOIN halls h ON h.id = e.hall_id
+		WHERE e.id = $1
+		FOR UPDATE OF e
+	`, eventID).Scan(&capacity); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT bs.seat_label
+		FROM booking_seats bs
+		JOIN bookings b ON b.id = bs.booking_id
+		WHERE b.event_id = $1 AND b.status = 'active'
+	`, eventID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var seat string
+		if err := rows.Scan(&seat); err != nil {
+			return err
+		}
+		taken[seat] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var conflicts []string
+	for _, seat := range seats {
+		if taken[seat] {
+			conflicts = append(conflicts, seat)
+		}
+	}
+	if len(conflicts) > 0 {
+		return &repository.SeatsTakenError{Seats: conflicts}
+	}
+	if available := capacity - len(taken); len(seats) > available {
+		return &repository.CapacityError{Available: max(available, 0)}
+	}
+	return nil
+}
+
+func (r *BookingRepository) Get(ctx context.Context, id uuid.UUID, userID uuid.UUID) (domain.Booking, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, event_id, status, total_price, currency, metadata, created_at, updated_at
+		FROM bookings
+		WHERE id = $1 AND user_id = $2
+	`, id, userID)
+	booking, err := scanBooking(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Booking{}, repository.ErrNotFound
+		}
+		return domain.Booking{}, err
+	}
+	return r.withSeats(ctx, booking)
+}
+
+func (r *BookingRepository) UpdateMetadata(ctx context.Context, id uuid.UUID, userID uuid.UUID, metadata json.RawMessage) (domain.Booking, error) {
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE bookings
+		SET metadata = $3, updated_at = now()
+		WHERE id = $1 AND user_id = $2
+		RETURNING id, user_id, event_id, status, total_price, currency, metadata, created_at, updated_at
+	`, id, userID, []byte(metadata))
+	booking, err := scanBooking(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Booking{}, repository.ErrNotFound
+		}
+		return domain.Booking{}, err
+	}
+	return r.withSeats(ctx, booking)
+}
+
+func (r *BookingRepository) withSeats(ctx context.Context, booking domain.Booking) (domain.Booking, error) {
+	seats, err := r.loadSeats(ctx, []domain.Booking{booking})
+	if err != nil {
+		return domain.Booking{}, err
+	}
+	booking.Seats = seats[booking.ID]
+	return booking, nil
+}
+
+func scanBooking(row rowScanner) (domain.Booking, error) {
+	var booking domain.Booking
+	var metadata []byte
+	if err := row.Scan(
+		&booking.ID,
+		&booking.UserID,
+		&booking.EventID,
+		&booking.Status,
+		&booking.TotalPrice,
+		&booking.Currency,
+		&metadata,
+		&booking.CreatedAt,
+		&booking.UpdatedAt,
+	); err != nil {
+		return domain.Booking{}, err
+	}
+	booking.Metadata = metadata
+	return booking, nil
+}
+
 func (r *BookingRepository) Cancel(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
 	result, err := r.db.ExecContext(ctx, `
 		UPDATE bookings
@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+type EmailVerificationRepository struct {
+	db *sql.DB
+}
+
+func NewEmailVerificationRepository(db *sql.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{db: db}
+}
+
+func (r *EmailVerificationRepository) Create(ctx context.Context, token domain.EmailVerificationToken) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO email_verification_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, token.UserID, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrConflict
+		}
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalid
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *EmailVerificationRepository) Consume(ctx context.Context, hash string, now time.Time) (uuid.UUID, error) {
+	var userID uuid.UUID
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE email_verification_tokens
+		SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING user_id
+	`, hash, now)
+	if err := row.Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.UUID{}, repository.ErrNotFound
+		}
+		return uuid.UUID{}, err
+	}
+	return userID, nil
+}
+
+func (r *EmailVerificationRepository) InvalidateForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE email_verification_tokens
+		SET used_at = now()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID)
+	return err
+}
+
+func (r *EmailVerificationRepository) ListCreatedSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT created_at
+		FROM email_verification_tokens
+		WHERE user_id = $1 AND created_at >= $2
+		ORDER BY created_at DESC
+	`, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var times []time.Time
+	for rows.Next() {
+		var createdAt time.Time
+		if err := rows.Scan(&createdAt); err != nil {
+			return nil, err
+		}
+		times = append(times, createdAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return times, nil
+}
@@ -1,9 +1,13 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 
 	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"islamdiplom/internal/logging"
 )
 
 func isUniqueViolation(err error) bool {
@@ -27,3 +31,33 @@ func isForeignKeyViolation(err error) bool {
 	}
 	return false
 }
+
+func isCheckViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23514"
+	}
+	return false
+}
+
+func isExclusionViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23P01"
+	}
+	return false
+}
+
+// rollback undoes a failed transaction. A rollback error leaves nothing more
+// to do, but it is logged because it can hide a broken connection.
+func rollback(ctx context.Context, tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		logging.FromContext(ctx).Warn("transaction rollback failed", zap.Error(err))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"islamdiplom/internal/domain"
@@ -20,7 +21,7 @@ func NewEventRepository(db *sql.DB) *EventRepository {
 
 func (r *EventRepository) List(ctx context.Context) ([]domain.Event, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, title, description, start_at, end_at, venue_id, published, created_at, updated_at
+		SELECT id, title, description, start_at, end_at, venue_id, hall_id, published, version, created_at, updated_at
 		FROM events
 		ORDER BY start_at ASC
 	`)
@@ -39,7 +40,9 @@ func (r *EventRepository) List(ctx context.Context) ([]domain.Event, error) {
 			&event.StartAt,
 			&event.EndAt,
 			&event.VenueID,
+			&event.HallID,
 			&event.Published,
+			&event.Version,
 			&event.CreatedAt,
 			&event.UpdatedAt,
 		); err != nil {
@@ -57,7 +60,7 @@ func (r *EventRepository) List(ctx context.Context) ([]domain.Event, error) {
 func (r *EventRepository) Get(ctx context.Context, id uuid.UUID) (domain.Event, error) {
 	var event domain.Event
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, title, description, start_at, end_at, venue_id, published, created_at, updated_at
+		SELECT id, title, description, start_at, end_at, venue_id, hall_id, published, version, created_at, updated_at
 		FROM events
 		WHERE id = $1
 	`, id)
@@ -68,7 +71,9 @@ func (r *EventRepository) Get(ctx context.Context, id uuid.UUID) (domain.Event,
 		&event.StartAt,
 		&event.EndAt,
 		&event.VenueID,
+		&event.HallID,
 		&event.Published,
+		&event.Version,
 		&event.CreatedAt,
 		&event.UpdatedAt,
 	); err != nil {
@@ -83,10 +88,10 @@ func (r *EventRepository) Get(ctx context.Context, id uuid.UUID) (domain.Event,
 
 func (r *EventRepository) Create(ctx context.Context, event domain.Event) (domain.Event, error) {
 	row := r.db.QueryRowContext(ctx, `
-		INSERT INTO events (title, description, start_at, end_at, venue_id, published)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, title, description, start_at, end_at, venue_id, published, created_at, updated_at
-	`, event.Title, event.Description, event.StartAt, event.EndAt, event.VenueID, event.Published)
+		INSERT INTO events (title, description, start_at, end_at, venue_id, hall_id, published)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, title, description, start_at, end_at, venue_id, hall_id, published, version, created_at, updated_at
+	`, event.Title, event.Description, event.StartAt, event.EndAt, event.VenueID, event.HallID, event.Published)
 
 	if err := row.Scan(
 		&event.ID,
@@ -95,13 +100,18 @@ func (r *EventRepository) Create(ctx context.Context, event domain.Event) (domai
 		&event.StartAt,
 		&event.EndAt,
 		&event.VenueID,
+		&event.HallID,
 		&event.Published,
+		&event.Version,
 		&event.CreatedAt,
 		&event.UpdatedAt,
 	); err != nil {
-		if isForeignKeyViolation(err) {
+		if isForeignKeyViolation(err) || isCheckViolation(err) {
 			return domain.Event{}, repository.ErrInvalid
 		}
+		if isExclusionViolation(err) {
+			return domain.Event{}, r.overlapError(ctx, event)
+		}
 		return domain.Event{}, err
 	}
 
@@ -116,11 +126,13 @@ func (r *EventRepository) Update(ctx context.Context, event domain.Event) (domai
 		    start_at = $3,
 		    end_at = $4,
 		    venue_id = $5,
-		    published = $6,
+		    hall_id = $6,
+		    published = $7,
+		    version = version + 1,
 		    updated_at = now()
-		WHERE id = $7
-		RETURNING id, title, description, start_at, end_at, venue_id, published, created_at, updated_at
-	`, event.Title, event.Description, event.StartAt, event.EndAt, event.VenueID, event.Published, event.ID)
+		WHERE id = $8 AND ($9 = 0 OR version = $9)
+		RETURNING id, title, description, start_at, end_at, venue_id, hall_id, published, version, created_at, updated_at
+	`, event.Title, event.Description, event.StartAt, event.EndAt, event.VenueID, event.HallID, event.Published, event.ID, event.Version)
 
 	if err := row.Scan(
 		&event.ID,
@@ -129,15 +141,20 @@ func (r *EventRepository) Update(ctx context.Context, event domain.Event) (domai
 		&event.StartAt,
 		&event.EndAt,
 		&event.VenueID,
+		&event.HallID,
 		&event.Published,
+		&event.Version,
 		&event.CreatedAt,
 		&event.UpdatedAt,
 	); err != nil {
-		if isForeignKeyViolation(err) {
+		if isForeignKeyViolation(err) || isCheckViolation(err) {
 			return domain.Event{}, repository.ErrInvalid
 		}
+		if isExclusionViolation(err) {
+			return domain.Event{}, r.overlapError(ctx, event)
+		}
 		if errors.Is(err, sql.ErrNoRows) {
-			return domain.Event{}, repository.ErrNotFound
+			return domain.Event{}, missingOrStale(ctx, r.db, "events", event.ID)
 		}
 		return domain.Event{}, err
 	}
@@ -145,6 +162,21 @@ func (r *EventRepository) Update(ctx context.Context, event domain.Event) (domai
 	return event, nil
 }
 
+// overlapError names the event that won a race for the hall. The
+// events_hall_no_overlap constraint only guards the raw time ranges; setup and
+// cleanup buffers are configuration, so the service checks them before the
+// write and two racing writes that only clash within the buffers both succeed.
+func (r *EventRepository) overlapError(ctx context.Context, event domain.Event) error {
+	clashes, err := r.ListOverlapping(ctx, event.HallID, event.StartAt, event.EndAt, event.ID)
+	if err != nil {
+		return err
+	}
+	if len(clashes) == 0 {
+		return repository.ErrConflict
+	}
+	return &repository.OverlapError{EventID: clashes[0].ID}
+}
+
 func (r *EventRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	result, err := r.db.ExecContext(ctx, `DELETE FROM events WHERE id = $1`, id)
 	if err != nil {
@@ -159,3 +191,99 @@ func (r *EventRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	}
 	return nil
 }
+
+func (r *EventRepository) ListOverlapping(ctx context.Context, hallID uuid.UUID, from, to time.Time, excludeID uuid.UUID) ([]domain.Event, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, title, description, start_at, end_at, venue_id, hall_id, published, version, created_at, updated_at
+		FROM events
+		WHERE hall_id = $1
+		  AND id <> $2
+		  AND start_at < $4
+		  AND end_at > $3
+		ORDER BY start_at ASC
+	`, hallID, excludeID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []domain.Event
+	for rows.Next() {
+		var event domain.Event
+		if err := rows.Scan(
+			&event.ID,
+			&event.Title,
+			&event.Description,
+			&event.StartAt,
+			&event.EndAt,
+			&event.VenueID,
+			&event.HallID,
+			&event.Published,
+			&event.Version,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *EventRepository) ListNear(ctx context.Context, lat, lng, radiusKm float64, after time.Time) ([]domain.NearbyEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, title, description, start_at, end_at, venue_id, hall_id, published, version, created_at, updated_at, distance_km
+		FROM (
+			SELECT e.id, e.title, e.description, e.start_at, e.end_at, e.venue_id, e.hall_id, e.published, e.version, e.created_at, e.updated_at,
+			       6371 * 2 * asin(LEAST(1, sqrt(
+			           power(sin(radians(v.latitude - $1) / 2), 2) +
+			           cos(radians($1)) * cos(radians(v.latitude)) *
+			           power(sin(radians(v.longitude - $2) / 2), 2)
+			       ))) AS distance_km
+			FROM events e
+			JOIN venues v ON v.id = e.venue_id
+			WHERE e.published
+			  AND v.latitude IS NOT NULL
+			  AND v.longitude IS NOT NULL
+			  AND v.latitude BETWEEN $1 - $3 / 111.045 AND $1 + $3 / 111.045
+			  AND e.end_at > $4
+		) nearby
+		WHERE distance_km <= $3
+		ORDER BY distance_km ASC, start_at ASC
+	`, lat, lng, radiusKm, after)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []domain.NearbyEvent
+	for rows.Next() {
+		var event domain.NearbyEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.Title,
+			&event.Description,
+			&event.StartAt,
+			&event.EndAt,
+			&event.VenueID,
+			&event.HallID,
+			&event.Published,
+			&event.Version,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+			&event.DistanceKm,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+type HallRepository struct {
+	db *sql.DB
+}
+
+func NewHallRepository(db *sql.DB) *HallRepository {
+	return &HallRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanHall(row rowScanner) (domain.Hall, error) {
+	var hall domain.Hall
+	var layout []byte
+	if err := row.Scan(
+		&hall.ID,
+		&hall.VenueID,
+		&hall.Name,
+		&hall.Capacity,
+		&layout,
+		&hall.CreatedAt,
+		&hall.UpdatedAt,
+	); err != nil {
+		return domain.Hall{}, err
+	}
+	if err := json.Unmarshal(layout, &hall.Layout); err != nil {
+		return domain.Hall{}, err
+	}
+	return hall, nil
+}
+
+func (r *HallRepository) ListByVenue(ctx context.Context, venueID uuid.UUID) ([]domain.Hall, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, venue_id, name, capacity, layout, created_at, updated_at
+		FROM halls
+		WHERE venue_id = $1
+		ORDER BY created_at ASC, name ASC
+	`, venueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var halls []domain.Hall
+	for rows.Next() {
+		hall, err := scanHall(rows)
+		if err != nil {
+			return nil, err
+		}
+		halls = append(halls, hall)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return halls, nil
+}
+
+func (r *HallRepository) Get(ctx context.Context, id uuid.UUID) (domain.Hall, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, venue_id, name, capacity, layout, created_at, updated_at
+		FROM halls
+		WHERE id = $1
+	`, id)
+	hall, err := scanHall(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Hall{}, repository.ErrNotFound
+		}
+		return domain.Hall{}, err
+	}
+
+	return hall, nil
+}
+
+func (r *HallRepository) Create(ctx context.Context, hall domain.Hall) (domain.Hall, error) {
+	layout, err := marshalLayout(hall.Layout)
+	if err != nil {
+		return domain.Hall{}, err
+	}
+
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO halls (venue_id, name, capacity, layout)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, venue_id, name, capacity, layout, created_at, updated_at
+	`, hall.VenueID, hall.Name, hall.Capacity, layout)
+
+	created, err := scanHall(row)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.Hall{}, repository.ErrConflict
+		}
+		if isForeignKeyViolation(err) || isCheckViolation(err) {
+			return domain.Hall{}, repository.ErrInvalid
+		}
+		return domain.Hall{}, err
+	}
+
+	return created, nil
+}
+
+func (r *HallRepository) Update(ctx context.Context, hall domain.Hall) (domain.Hall, error) {
+	layout, err := marshalLayout(hall.Layout)
+	if err != nil {
+		return domain.Hall{}, err
+	}
+
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE halls
+		SET name = $1,
+		    capacity = $2,
+		    layout = $3,
+		    updated_at = now()
+		WHERE id = $4
+		RETURNING id, venue_id, name, capacity, layout, created_at, updated_at
+	`, hall.Name, hall.Capacity, layout, hall.ID)
+
+	updated, err := scanHall(row)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.Hall{}, repository.ErrConflict
+		}
+		if isCheckViolation(err) {
+			return domain.Hall{}, repository.ErrInvalid
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Hall{}, repository.ErrNotFound
+		}
+		return domain.Hall{}, err
+	}
+
+	return updated, nil
+}
+
+func (r *HallRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM halls WHERE id = $1`, id)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return repository.ErrConflict
+		}
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func marshalLayout(layout []domain.SeatRow) ([]byte, error) {
+	if layout == nil {
+		layout = []domain.SeatRow{}
+	}
+	return json.Marshal(layout)
+}
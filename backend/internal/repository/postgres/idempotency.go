@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"islamdiplom/internal/domain"
+)
+
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error) {
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE subject = $1 AND key = $2 AND expires_at <= $3
+	`, record.Subject, record.Key, record.CreatedAt); err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	}
+
+	// A retry of the same request takes over a reservation whose lease has
+	// run out without a stored response.
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (subject, key, fingerprint, created_at, expires_at, locked_until)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (subject, key) DO UPDATE
+		SET created_at = EXCLUDED.created_at,
+		    expires_at = EXCLUDED.expires_at,
+		    locked_until = EXCLUDED.locked_until
+		WHERE idempotency_keys.status_code = 0
+		  AND idempotency_keys.locked_until <= EXCLUDED.created_at
+		  AND idempotency_keys.fingerprint = EXCLUDED.fingerprint
+	`, record.Subject, record.Key, record.Fingerprint, record.CreatedAt, record.ExpiresAt, record.LockedUntil)
+	if err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	} else if affected == 1 {
+		return record, true, nil
+	}
+
+	var existing domain.IdempotencyRecord
+	err = r.db.QueryRowContext(ctx, `
+		SELECT subject, key, fingerprint, status_code, content_type, body, created_at, expires_at, locked_until
+		FROM idempotency_keys
+		WHERE subject = $1 AND key = $2
+	`, record.Subject, record.Key).Scan(
+		&existing.Subject,
+		&existing.Key,
+		&existing.Fingerprint,
+		&existing.StatusCode,
+		&existing.ContentType,
+		&existing.Body,
+		&existing.CreatedAt,
+		&existing.ExpiresAt,
+		&existing.LockedUntil,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Released between the insert and the select, so try again.
+		return r.Reserve(ctx, record)
+	}
+	if err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	}
+	return existing, false, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, subject, key string, statusCode int, contentType string, body []byte) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, body = $5
+		WHERE subject = $1 AND key = $2
+	`, subject, key, statusCode, contentType, body)
+	return err
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, subject, key string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE subject = $1 AND key = $2 AND status_code = 0`, subject, key)
+	return err
+}
+
+func (r *IdempotencyRepository) Prune(ctx context.Context, before time.Time) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, before)
+	return err
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"islamdiplom/internal/domain"
+)
+
+type LoginAttemptRepository struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository(db *sql.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+func (r *LoginAttemptRepository) RecordFailure(ctx context.Context, attempt domain.LoginAttempt) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO failed_logins (email, ip, created_at)
+		VALUES ($1, $2, $3)
+	`, attempt.Email, attempt.IP, attempt.CreatedAt)
+	return err
+}
+
+func (r *LoginAttemptRepository) CountFailures(ctx context.Context, email, ip string, since time.Time) (domain.LoginFailures, error) {
+	var failures domain.LoginFailures
+	var oldestByEmail, oldestByIP sql.NullTime
+	row := r.db.QueryRowContext(ctx, `
+		SELECT
+		  count(*) FILTER (WHERE email = $1 AND cleared_at IS NULL),
+		  count(*) FILTER (WHERE ip = $2),
+		  min(created_at) FILTER (WHERE email = $1 AND cleared_at IS NULL),
+		  min(created_at) FILTER (WHERE ip = $2)
+		FROM failed_logins
+		WHERE created_at >= $3 AND (email = $1 OR ip = $2)
+	`, email, ip, since)
+	if err := row.Scan(&failures.ByEmail, &failures.ByIP, &oldestByEmail, &oldestByIP); err != nil {
+		return domain.LoginFailures{}, err
+	}
+	failures.OldestByEmail = oldestByEmail.Time
+	failures.OldestByIP = oldestByIP.Time
+	return failures, nil
+}
+
+func (r *LoginAttemptRepository) Clear(ctx context.Context, email string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE failed_logins
+		SET cleared_at = now()
+		WHERE email = $1 AND cleared_at IS NULL
+	`, email)
+	return err
+}
+
+func (r *LoginAttemptRepository) List(ctx context.Context, email string, limit int) ([]domain.LoginAttempt, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, email, ip, created_at, cleared_at
+		FROM failed_logins
+		WHERE $1 = '' OR email = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, email, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []domain.LoginAttempt
+	for rows.Next() {
+		var attempt domain.LoginAttempt
+		var clearedAt sql.NullTime
+		if err := rows.Scan(
+			&attempt.ID,
+			&attempt.Email,
+			&attempt.IP,
+			&attempt.CreatedAt,
+			&clearedAt,
+		); err != nil {
+			return nil, err
+		}
+		if clearedAt.Valid {
+			attempt.ClearedAt = &clearedAt.Time
+		}
+		attempts = append(attempts, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+type OIDCRepository struct {
+	db *sql.DB
+}
+
+func NewOIDCRepository(db *sql.DB) *OIDCRepository {
+	return &OIDCRepository{db: db}
+}
+
+func (r *OIDCRepository) CreateState(ctx context.Context, state domain.OIDCLoginState) error {
+	// Abandoned logins leave their state behind; sweep them on the way in.
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at <= now()`); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oidc_login_states (state_hash, provider, code_verifier, nonce, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, state.StateHash, state.Provider, state.CodeVerifier, state.Nonce, state.ExpiresAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *OIDCRepository) ConsumeState(ctx context.Context, stateHash string, now time.Time) (domain.OIDCLoginState, error) {
+	var state domain.OIDCLoginState
+	row := r.db.QueryRowContext(ctx, `
+		DELETE FROM oidc_login_states
+		WHERE state_hash = $1 AND expires_at > $2
+		RETURNING state_hash, provider, code_verifier, nonce, expires_at
+	`, stateHash, now)
+	if err := row.Scan(&state.StateHash, &state.Provider, &state.CodeVerifier, &state.Nonce, &state.ExpiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.OIDCLoginState{}, repository.ErrNotFound
+		}
+		return domain.OIDCLoginState{}, err
+	}
+	return state, nil
+}
+
+func (r *OIDCRepository) GetIdentity(ctx context.Context, provider, subject string) (domain.ExternalIdentity, error) {
+	var identity domain.ExternalIdentity
+	row := r.db.QueryRowContext(ctx, `
+		SELECT provider, subject, user_id, email, created_at, last_login_at
+		FROM oidc_identities
+		WHERE provider = $1 AND subject = $2
+	`, provider, subject)
+	if err := row.Scan(&identity.Provider, &identity.Subject, &identity.UserID, &identity.Email, &identity.CreatedAt, &identity.LastLoginAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ExternalIdentity{}, repository.ErrNotFound
+		}
+		return domain.ExternalIdentity{}, err
+	}
+	return identity, nil
+}
+
+func (r *OIDCRepository) LinkIdentity(ctx context.Context, identity domain.ExternalIdentity) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oidc_identities (provider, subject, user_id, email)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (provider, subject) DO UPDATE
+		SET email = EXCLUDED.email,
+		    last_login_at = now()
+		WHERE oidc_identities.user_id = EXCLUDED.user_id
+	`, identity.Provider, identity.Subject, identity.UserID, identity.Email)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"islamdiplom/internal/domain"
+)
+
+type RateLimitRepository struct {
+	db *sql.DB
+}
+
+func NewRateLimitRepository(db *sql.DB) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Take locks the bucket row so replicas sharing the database see each
+// other's requests.
+func (r *RateLimitRepository) Take(ctx context.Context, key string, policy domain.RateLimitPolicy, now time.Time) (decision domain.RateLimitDecision, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.RateLimitDecision{}, err
+	}
+	defer func() {
+		if err != nil {
+			rollback(ctx, tx)
+		}
+	}()
+
+	full := domain.FullRateLimitBucket(policy, now)
+	if _, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO NOTHING
+	`, key, full.Tokens, full.UpdatedAt); err != nil {
+		return domain.RateLimitDecision{}, err
+	}
+
+	var bucket domain.RateLimitBucket
+	if err = tx.QueryRowContext(ctx, `
+		SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE
+	`, key).Scan(&bucket.Tokens, &bucket.UpdatedAt); err != nil {
+		return domain.RateLimitDecision{}, err
+	}
+
+	bucket, decision = bucket.Take(policy, now)
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3 WHERE key = $1
+	`, key, bucket.Tokens, bucket.UpdatedAt); err != nil {
+		return domain.RateLimitDecision{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.RateLimitDecision{}, err
+	}
+	return decision, nil
+}
+
+func (r *RateLimitRepository) Prune(ctx context.Context, before time.Time) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < $1`, before)
+	return err
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+type RefreshTokenRepository struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenRepository(db *sql.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+func scanRefreshToken(row rowScanner) (domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	var revokedAt sql.NullTime
+	var replacedBy uuid.NullUUID
+	if err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&revokedAt,
+		&replacedBy,
+	); err != nil {
+		return domain.RefreshToken{}, err
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	if replacedBy.Valid {
+		token.ReplacedBy = &replacedBy.UUID
+	}
+	return token, nil
+}
+
+func (r *RefreshTokenRepository) Create(ctx context.Context, token domain.RefreshToken) (domain.RefreshToken, error) {
+	return insertRefreshToken(ctx, r.db, token)
+}
+
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, hash string) (domain.RefreshToken, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, family_id, token_hash, expires_at, created_at, revoked_at, replaced_by
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`, hash)
+	token, err := scanRefreshToken(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.RefreshToken{}, repository.ErrNotFound
+		}
+		return domain.RefreshToken{}, err
+	}
+	return token, nil
+}
+
+func (r *RefreshTokenRepository) Rotate(ctx context.Context, currentID uuid.UUID, next domain.RefreshToken) (domain.RefreshToken, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.RefreshToken{}, err
+	}
+	defer func() {
+		if err != nil {
+			rollback(ctx, tx)
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE id = $1 AND revoked_at IS NULL
+	`, currentID)
+	if err != nil {
+		return domain.RefreshToken{}, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain.RefreshToken{}, err
+	}
+	if rows == 0 {
+		err = repository.ErrConflict
+		return domain.RefreshToken{}, err
+	}
+
+	created, err := insertRefreshToken(ctx, tx, next)
+	if err != nil {
+		return domain.RefreshToken{}, err
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET replaced_by = $1 WHERE id = $2
+	`, created.ID, currentID); err != nil {
+		return domain.RefreshToken{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.RefreshToken{}, err
+	}
+
+	return created, nil
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`, familyID)
+	return err
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	return err
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertRefreshToken(ctx context.Context, q queryRower, token domain.RefreshToken) (domain.RefreshToken, error) {
+	row := q.QueryRowContext(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, user_id, family_id, token_hash, expires_at, created_at, revoked_at, replaced_by
+	`, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt)
+	created, err := scanRefreshToken(row)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.RefreshToken{}, repository.ErrConflict
+		}
+		if isForeignKeyViolation(err) {
+			return domain.RefreshToken{}, repository.ErrInvalid
+		}
+		return domain.RefreshToken{}, err
+	}
+	return created, nil
+}
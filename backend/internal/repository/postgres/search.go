@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"islamdiplom/internal/domain"
+)
+
+type SearchRepository struct {
+	db *sql.DB
+}
+
+func NewSearchRepository(db *sql.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+func (r *SearchRepository) Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH q AS (
+			SELECT to_tsquery('russian', $1) || to_tsquery('simple', $1) AS query
+		)
+		SELECT kind, id, title, snippet, rank
+		FROM (
+			SELECT 'event' AS kind, e.id, e.title,
+			       ts_headline('russian', e.title || '. ' || e.description, q.query,
+			           'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5') AS snippet,
+			       ts_rank(e.search_vector, q.query) AS rank
+			FROM events e, q
+			WHERE e.published
+			  AND (e.search_vector @@ q.query
+			   OR EXISTS (
+			       SELECT 1
+			       FROM event_categories ec
+			       JOIN categories c ON c.id = ec.category_id
+			       WHERE ec.event_id = e.id AND c.search_vector @@ q.query
+			   ))
+			UNION ALL
+			SELECT 'venue', v.id, v.name,
+			       ts_headline('russian', v.name || ', ' || v.address, q.query,
+			           'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+			       ts_rank(v.search_vector, q.query)
+			FROM venues v, q
+			WHERE v.search_vector @@ q.query
+			UNION ALL
+			SELECT 'category', c.id, c.name,
+			       ts_headline('russian', c.name, q.query,
+			           'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+			       ts_rank(c.search_vector, q.query)
+			FROM categories c, q
+			WHERE c.search_vector @@ q.query
+		) results
+		ORDER BY rank DESC, title ASC
+		LIMIT $2
+	`, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.SearchResult
+	for rows.Next() {
+		var result domain.SearchResult
+		if err := rows.Scan(
+			&result.Kind,
+			&result.ID,
+			&result.Title,
+			&result.Snippet,
+			&result.Rank,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func scanSession(row rowScanner) (domain.Session, error) {
+	var session domain.Session
+	var revokedAt sql.NullTime
+	if err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IP,
+		&session.CreatedAt,
+		&session.LastActiveAt,
+		&revokedAt,
+	); err != nil {
+		return domain.Session{}, err
+	}
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return session, nil
+}
+
+func (r *SessionRepository) Create(ctx context.Context, session domain.Session) (domain.Session, error) {
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO sessions (id, user_id, user_agent, ip, created_at, last_active_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING id, user_id, user_agent, ip, created_at, last_active_at, revoked_at
+	`, session.ID, session.UserID, session.UserAgent, session.IP, session.CreatedAt)
+	created, err := scanSession(row)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.Session{}, repository.ErrConflict
+		}
+		if isForeignKeyViolation(err) {
+			return domain.Session{}, repository.ErrNotFound
+		}
+		return domain.Session{}, err
+	}
+	return created, nil
+}
+
+func (r *SessionRepository) Get(ctx context.Context, id uuid.UUID) (domain.Session, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, user_agent, ip, created_at, last_active_at, revoked_at
+		FROM sessions
+		WHERE id = $1
+	`, id)
+	session, err := scanSession(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Session{}, repository.ErrNotFound
+		}
+		return domain.Session{}, err
+	}
+	return session, nil
+}
+
+func (r *SessionRepository) ListActive(ctx context.Context, userID uuid.UUID, since time.Time) ([]domain.Session, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, user_agent, ip, created_at, last_active_at, revoked_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND last_active_at > $2
+		ORDER BY last_active_at DESC
+	`, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []domain.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *SessionRepository) Touch(ctx context.Context, id uuid.UUID, ip string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE sessions
+		SET last_active_at = $3,
+		    ip = CASE WHEN $2 = '' THEN ip ELSE $2 END
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id, ip, at)
+	return err
+}
+
+func (r *SessionRepository) Revoke(ctx context.Context, id, userID uuid.UUID, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE sessions
+		SET revoked_at = $3
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID, at)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE sessions
+		SET revoked_at = $2
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID, at)
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"islamdiplom/internal/domain"
@@ -18,31 +19,44 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+func scanUser(row rowScanner) (domain.User, error) {
+	var user domain.User
+	var verifiedAt sql.NullTime
+	if err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.Name, &user.Phone, &user.PreferredLanguage, &verifiedAt, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return domain.User{}, err
+	}
+	if verifiedAt.Valid {
+		user.EmailVerifiedAt = &verifiedAt.Time
+	}
+	return user, nil
+}
+
 func (r *UserRepository) Create(ctx context.Context, user domain.User) (domain.User, error) {
 	row := r.db.QueryRowContext(ctx, `
 		INSERT INTO users (email, password_hash)
 		VALUES ($1, $2)
-		RETURNING id, email, password_hash, created_at, updated_at
+		RETURNING id, email, password_hash, role, name, phone, preferred_language, email_verified_at, created_at, updated_at
 	`, user.Email, user.PasswordHash)
 
-	if err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	created, err := scanUser(row)
+	if err != nil {
 		if isUniqueViolation(err) {
 			return domain.User{}, repository.ErrConflict
 		}
 		return domain.User{}, err
 	}
 
-	return user, nil
+	return created, nil
 }
 
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (domain.User, error) {
-	var user domain.User
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, email, password_hash, created_at, updated_at
+		SELECT id, email, password_hash, role, name, phone, preferred_language, email_verified_at, created_at, updated_at
 		FROM users
-		WHERE email = $1
+		WHERE email = $1 AND deleted_at IS NULL
 	`, email)
-	if err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	user, err := scanUser(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.User{}, repository.ErrNotFound
 		}
@@ -53,13 +67,13 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (domain.U
 }
 
 func (r *UserRepository) Get(ctx context.Context, id uuid.UUID) (domain.User, error) {
-	var user domain.User
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, email, password_hash, created_at, updated_at
+		SELECT id, email, password_hash, role, name, phone, preferred_language, email_verified_at, created_at, updated_at
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`, id)
-	if err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	user, err := scanUser(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.User{}, repository.ErrNotFound
 		}
@@ -68,3 +82,171 @@ func (r *UserRepository) Get(ctx context.Context, id uuid.UUID) (domain.User, er
 
 	return user, nil
 }
+
+func (r *UserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE users
+		SET password_hash = $1,
+		    updated_at = now()
+		WHERE id = $2
+	`, passwordHash, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, id uuid.UUID, role string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE users
+		SET role = $1,
+		    updated_at = now()
+		WHERE id = $2
+	`, role, id)
+	if err != nil {
+		if isCheckViolation(err) {
+			return repository.ErrInvalid
+		}
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, $1),
+		    updated_at = now()
+		WHERE id = $2
+	`, at, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdateProfile(ctx context.Context, user domain.User) (domain.User, error) {
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE users
+		SET name = $1,
+		    phone = $2,
+		    preferred_language = $3,
+		    updated_at = now()
+		WHERE id = $4 AND deleted_at IS NULL
+		RETURNING id, email, password_hash, role, name, phone, preferred_language, email_verified_at, created_at, updated_at
+	`, user.Name, user.Phone, user.PreferredLanguage, user.ID)
+	updated, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.User{}, repository.ErrNotFound
+		}
+		return domain.User{}, err
+	}
+	return updated, nil
+}
+
+// Anonymize strips personal data and every credential from the account but
+// keeps the row, so bookings still reference a user for accounting. Active
+// bookings for events that have not started yet are canceled to free seats.
+func (r *UserRepository) Anonymize(ctx context.Context, id uuid.UUID, at time.Time) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			rollback(ctx, tx)
+		}
+	}()
+
+	var email string
+	row := tx.QueryRowContext(ctx, `
+		SELECT email
+		FROM users
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`, id)
+	if err = row.Scan(&email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = repository.ErrNotFound
+		}
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE users
+		SET email = 'deleted-' || id::text || '@deleted.invalid',
+		    password_hash = '',
+		    name = '',
+		    phone = '',
+		    role = 'user',
+		    email_verified_at = NULL,
+		    deleted_at = $2,
+		    updated_at = $2
+		WHERE id = $1
+	`, id, at); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE bookings
+		SET status = 'canceled', updated_at = $2
+		WHERE user_id = $1
+		  AND status = 'active'
+		  AND event_id IN (SELECT id FROM events WHERE start_at > $2)
+	`, id, at); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE bookings SET metadata = '{}'::jsonb WHERE user_id = $1 AND metadata <> '{}'::jsonb
+	`, id); err != nil {
+		return err
+	}
+
+	for _, query := range []string{
+		`DELETE FROM refresh_tokens WHERE user_id = $1`,
+		`DELETE FROM sessions WHERE user_id = $1`,
+		`DELETE FROM password_reset_tokens WHERE user_id = $1`,
+		`DELETE FROM email_verification_tokens WHERE user_id = $1`,
+		`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM user_mfa WHERE user_id = $1`,
+		`DELETE FROM oidc_identities WHERE user_id = $1`,
+	} {
+		if _, err = tx.ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, $2)
+		WHERE user_id = $1
+	`, id, at); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM failed_logins WHERE email = $1`, email); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
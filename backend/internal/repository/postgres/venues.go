@@ -18,9 +18,33 @@ func NewVenueRepository(db *sql.DB) *VenueRepository {
 	return &VenueRepository{db: db}
 }
 
+func scanVenue(row rowScanner) (domain.Venue, error) {
+	var venue domain.Venue
+	var latitude, longitude sql.NullFloat64
+	if err := row.Scan(
+		&venue.ID,
+		&venue.Name,
+		&venue.Address,
+		&venue.City,
+		&latitude,
+		&longitude,
+		&venue.Timezone,
+		&venue.Version,
+		&venue.CreatedAt,
+		&venue.UpdatedAt,
+	); err != nil {
+		return domain.Venue{}, err
+	}
+	if latitude.Valid && longitude.Valid {
+		venue.Latitude = &latitude.Float64
+		venue.Longitude = &longitude.Float64
+	}
+	return venue, nil
+}
+
 func (r *VenueRepository) List(ctx context.Context) ([]domain.Venue, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, name, address, created_at, updated_at
+		SELECT id, name, address, city, latitude, longitude, timezone, version, created_at, updated_at
 		FROM venues
 		ORDER BY name ASC
 	`)
@@ -31,14 +55,8 @@ func (r *VenueRepository) List(ctx context.Context) ([]domain.Venue, error) {
 
 	var venues []domain.Venue
 	for rows.Next() {
-		var venue domain.Venue
-		if err := rows.Scan(
-			&venue.ID,
-			&venue.Name,
-			&venue.Address,
-			&venue.CreatedAt,
-			&venue.UpdatedAt,
-		); err != nil {
+		venue, err := scanVenue(rows)
+		if err != nil {
 			return nil, err
 		}
 		venues = append(venues, venue)
@@ -51,19 +69,13 @@ func (r *VenueRepository) List(ctx context.Context) ([]domain.Venue, error) {
 }
 
 func (r *VenueRepository) Get(ctx context.Context, id uuid.UUID) (domain.Venue, error) {
-	var venue domain.Venue
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, name, address, created_at, updated_at
+		SELECT id, name, address, city, latitude, longitude, timezone, version, created_at, updated_at
 		FROM venues
 		WHERE id = $1
 	`, id)
-	if err := row.Scan(
-		&venue.ID,
-		&venue.Name,
-		&venue.Address,
-		&venue.CreatedAt,
-		&venue.UpdatedAt,
-	); err != nil {
+	venue, err := scanVenue(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Venue{}, repository.ErrNotFound
 		}
@@ -77,32 +89,30 @@ func (r *VenueRepository) Create(ctx context.Context, venue domain.Venue) (domai
 	var row *sql.Row
 	if venue.ID == uuid.Nil {
 		row = r.db.QueryRowContext(ctx, `
-			INSERT INTO venues (name, address)
-			VALUES ($1, $2)
-			RETURNING id, name, address, created_at, updated_at
-		`, venue.Name, venue.Address)
+			INSERT INTO venues (name, address, city, latitude, longitude, timezone)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, name, address, city, latitude, longitude, timezone, version, created_at, updated_at
+		`, venue.Name, venue.Address, venue.City, venue.Latitude, venue.Longitude, venue.Timezone)
 	} else {
 		row = r.db.QueryRowContext(ctx, `
-			INSERT INTO venues (id, name, address)
-			VALUES ($1, $2, $3)
-			RETURNING id, name, address, created_at, updated_at
-		`, venue.ID, venue.Name, venue.Address)
+			INSERT INTO venues (id, name, address, city, latitude, longitude, timezone)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id, name, address, city, latitude, longitude, timezone, version, created_at, updated_at
+		`, venue.ID, venue.Name, venue.Address, venue.City, venue.Latitude, venue.Longitude, venue.Timezone)
 	}
 
-	if err := row.Scan(
-		&venue.ID,
-		&venue.Name,
-		&venue.Address,
-		&venue.CreatedAt,
-		&venue.UpdatedAt,
-	); err != nil {
+	created, err := scanVenue(row)
+	if err != nil {
 		if isUniqueViolation(err) {
 			return domain.Venue{}, repository.ErrConflict
 		}
+		if isCheckViolation(err) {
+			return domain.Venue{}, repository.ErrInvalid
+		}
 		return domain.Venue{}, err
 	}
 
-	return venue, nil
+	return created, nil
 }
 
 func (r *VenueRepository) Update(ctx context.Context, venue domain.Venue) (domain.Venue, error) {
@@ -110,25 +120,40 @@ func (r *VenueRepository) Update(ctx context.Context, venue domain.Venue) (domai
 		UPDATE venues
 		SET name = $1,
 		    address = $2,
+		    city = $3,
+		    latitude = $4,
+		    longitude = $5,
+		    timezone = $6,
+		    version = version + 1,
 		    updated_at = now()
-		WHERE id = $3
-		RETURNING id, name, address, created_at, updated_at
-	`, venue.Name, venue.Address, venue.ID)
+		WHERE id = $7 AND ($8 = 0 OR version = $8)
+		RETURNING id, name, address, city, latitude, longitude, timezone, version, created_at, updated_at
+	`, venue.Name, venue.Address, venue.City, venue.Latitude, venue.Longitude, venue.Timezone, venue.ID, venue.Version)
 
-	if err := row.Scan(
-		&venue.ID,
-		&venue.Name,
-		&venue.Address,
-		&venue.CreatedAt,
-		&venue.UpdatedAt,
-	); err != nil {
+	updated, err := scanVenue(row)
+	if err != nil {
+		if isCheckViolation(err) {
+			return domain.Venue{}, repository.ErrInvalid
+		}
 		if errors.Is(err, sql.ErrNoRows) {
-			return domain.Venue{}, repository.ErrNotFound
+			return domain.Venue{}, missingOrStale(ctx, r.db, "venues", venue.ID)
 		}
 		return domain.Venue{}, err
 	}
 
-	return venue, nil
+	return updated, nil
+}
+
+// missingOrStale explains why a versioned update matched no row.
+func missingOrStale(ctx context.Context, db *sql.DB, table string, id uuid.UUID) error {
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return repository.ErrPreconditionFailed
+	}
+	return repository.ErrNotFound
 }
 
 func (r *VenueRepository) Delete(ctx context.Context, id uuid.UUID) error {
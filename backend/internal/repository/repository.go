@@ -2,17 +2,24 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 	"islamdiplom/internal/domain"
 )
 
+// Update on events and venues applies only when Version is zero or equals the
+// stored version, failing with ErrPreconditionFailed otherwise, and
+// increments the stored version.
 type EventRepository interface {
 	List(ctx context.Context) ([]domain.Event, error)
 	Get(ctx context.Context, id uuid.UUID) (domain.Event, error)
 	Create(ctx context.Context, event domain.Event) (domain.Event, error)
 	Update(ctx context.Context, event domain.Event) (domain.Event, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	ListOverlapping(ctx context.Context, hallID uuid.UUID, from, to time.Time, excludeID uuid.UUID) ([]domain.Event, error)
+	ListNear(ctx context.Context, lat, lng, radiusKm float64, after time.Time) ([]domain.NearbyEvent, error)
 }
 
 type VenueRepository interface {
@@ -23,6 +30,14 @@ type VenueRepository interface {
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
+type HallRepository interface {
+	ListByVenue(ctx context.Context, venueID uuid.UUID) ([]domain.Hall, error)
+	Get(ctx context.Context, id uuid.UUID) (domain.Hall, error)
+	Create(ctx context.Context, hall domain.Hall) (domain.Hall, error)
+	Update(ctx context.Context, hall domain.Hall) (domain.Hall, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
 type CategoryRepository interface {
 	List(ctx context.Context) ([]domain.Category, error)
 	Get(ctx context.Context, id uuid.UUID) (domain.Category, error)
@@ -32,11 +47,105 @@ type UserRepository interface {
 	Create(ctx context.Context, user domain.User) (domain.User, error)
 	GetByEmail(ctx context.Context, email string) (domain.User, error)
 	Get(ctx context.Context, id uuid.UUID) (domain.User, error)
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	UpdateRole(ctx context.Context, id uuid.UUID, role string) error
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, at time.Time) error
+	UpdateProfile(ctx context.Context, user domain.User) (domain.User, error)
+	Anonymize(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token domain.RefreshToken) (domain.RefreshToken, error)
+	GetByHash(ctx context.Context, hash string) (domain.RefreshToken, error)
+	Rotate(ctx context.Context, currentID uuid.UUID, next domain.RefreshToken) (domain.RefreshToken, error)
+	RevokeFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session domain.Session) (domain.Session, error)
+	Get(ctx context.Context, id uuid.UUID) (domain.Session, error)
+	ListActive(ctx context.Context, userID uuid.UUID, since time.Time) ([]domain.Session, error)
+	Touch(ctx context.Context, id uuid.UUID, ip string, at time.Time) error
+	Revoke(ctx context.Context, id, userID uuid.UUID, at time.Time) error
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID, at time.Time) error
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, token domain.PasswordResetToken) error
+	Consume(ctx context.Context, hash string, now time.Time) (uuid.UUID, error)
+	InvalidateForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, token domain.EmailVerificationToken) error
+	Consume(ctx context.Context, hash string, now time.Time) (uuid.UUID, error)
+	InvalidateForUser(ctx context.Context, userID uuid.UUID) error
+	ListCreatedSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]time.Time, error)
+}
+
+type LoginAttemptRepository interface {
+	RecordFailure(ctx context.Context, attempt domain.LoginAttempt) error
+	CountFailures(ctx context.Context, email, ip string, since time.Time) (domain.LoginFailures, error)
+	Clear(ctx context.Context, email string) error
+	List(ctx context.Context, email string, limit int) ([]domain.LoginAttempt, error)
+}
+
+type MFARepository interface {
+	Get(ctx context.Context, userID uuid.UUID) (domain.MFA, error)
+	SavePending(ctx context.Context, userID uuid.UUID, secret string) error
+	Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error
+	Disable(ctx context.Context, userID uuid.UUID) error
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) error
+}
+
+type OIDCRepository interface {
+	CreateState(ctx context.Context, state domain.OIDCLoginState) error
+	ConsumeState(ctx context.Context, stateHash string, now time.Time) (domain.OIDCLoginState, error)
+	GetIdentity(ctx context.Context, provider, subject string) (domain.ExternalIdentity, error)
+	LinkIdentity(ctx context.Context, identity domain.ExternalIdentity) error
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key domain.APIKey) (domain.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (domain.APIKey, error)
+	List(ctx context.Context) ([]domain.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+// RateLimitRepository applies Take to the bucket stored under key
+// atomically, starting from a full bucket for unknown keys.
+type RateLimitRepository interface {
+	Take(ctx context.Context, key string, policy domain.RateLimitPolicy, now time.Time) (domain.RateLimitDecision, error)
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// IdempotencyRepository.Reserve stores record unless an unexpired record
+// with the same subject and key exists, in which case that one is returned
+// with created set to false.
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, record domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, subject, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, subject, key string) error
+	Prune(ctx context.Context, before time.Time) error
+}
+
+type AuditRepository interface {
+	Append(ctx context.Context, entry domain.AuditEntry) error
+	List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
 }
 
 type BookingRepository interface {
 	ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Booking, error)
+	Get(ctx context.Context, id uuid.UUID, userID uuid.UUID) (domain.Booking, error)
 	Create(ctx context.Context, booking domain.Booking) (domain.Booking, error)
+	UpdateMetadata(ctx context.Context, id uuid.UUID, userID uuid.UUID, metadata json.RawMessage) (domain.Booking, error)
 	Cancel(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
 	ListSeatsByEvent(ctx context.Context, eventID uuid.UUID) ([]string, error)
 }
+
+type SearchRepository interface {
+	Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/logging"
+	"islamdiplom/internal/repository"
+)
+
+const (
+	apiKeyMarker    = "idk_"
+	apiKeyPrefixLen = len(apiKeyMarker) + 8
+)
+
+type APIKeyInput struct {
+	Name      string
+	UserID    uuid.UUID
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+type APIKeyService struct {
+	keys  repository.APIKeyRepository
+	users repository.UserRepository
+	audit *AuditLog
+}
+
+func NewAPIKeyService(keys repository.APIKeyRepository, users repository.UserRepository, audit *AuditLog) *APIKeyService {
+	return &APIKeyService{keys: keys, users: users, audit: audit}
+}
+
+// Create issues a key acting as input.UserID. Only a hash is stored, so the
+// returned raw key cannot be recovered later.
+func (s *APIKeyService) Create(ctx context.Context, createdBy uuid.UUID, input APIKeyInput) (string, domain.APIKey, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || input.UserID == uuid.Nil {
+		return "", domain.APIKey{}, repository.ErrInvalid
+	}
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return "", domain.APIKey{}, err
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return "", domain.APIKey{}, repository.ErrInvalid
+	}
+	if _, err := s.users.Get(ctx, input.UserID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", domain.APIKey{}, repository.ErrInvalid
+		}
+		return "", domain.APIKey{}, err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", domain.APIKey{}, err
+	}
+	raw := apiKeyMarker + token
+
+	key, err := s.keys.Create(ctx, domain.APIKey{
+		Name:      name,
+		Prefix:    raw[:apiKeyPrefixLen],
+		KeyHash:   hashToken(raw),
+		UserID:    input.UserID,
+		Scopes:    scopes,
+		CreatedBy: &createdBy,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		return "", domain.APIKey{}, err
+	}
+	s.audit.Record(ctx, domain.AuditCreate, domain.AuditEntityAPIKey, key.ID.String(), nil, key)
+	return raw, key, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context) ([]domain.APIKey, error) {
+	return s.keys.List(ctx)
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, id uuid.UUID) error {
+	at := time.Now().UTC()
+	if err := s.keys.Revoke(ctx, id, at); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditRevoke, domain.AuditEntityAPIKey, id.String(), nil, map[string]time.Time{"revokedAt": at})
+	return nil
+}
+
+func (s *APIKeyService) Authenticate(ctx context.Context, raw string) (domain.APIKey, error) {
+	if !strings.HasPrefix(raw, apiKeyMarker) {
+		return domain.APIKey{}, repository.ErrUnauthorized
+	}
+
+	key, err := s.keys.GetByHash(ctx, hashToken(raw))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return domain.APIKey{}, repository.ErrUnauthorized
+		}
+		return domain.APIKey{}, err
+	}
+
+	now := time.Now().UTC()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+		return domain.APIKey{}, repository.ErrUnauthorized
+	}
+
+	// Usage tracking must not fail the request it describes.
+	if err := s.keys.TouchLastUsed(ctx, key.ID, now); err != nil {
+		logging.FromContext(ctx).Warn("api key touch failed", zap.String("apiKeyId", key.ID.String()), zap.Error(err))
+	}
+	return key, nil
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	known := make(map[string]bool, len(domain.APIKeyScopes))
+	for _, scope := range domain.APIKeyScopes {
+		known[scope] = true
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !known[scope] {
+			return nil, repository.ErrInvalid
+		}
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+		result = append(result, scope)
+	}
+	if len(result) == 0 {
+		return nil, repository.ErrInvalid
+	}
+	sort.Strings(result)
+	return result, nil
+}
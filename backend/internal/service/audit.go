@@ -0,0 +1,171 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/logging"
+	"islamdiplom/internal/repository"
+)
+
+const maxAuditPage = 500
+
+// Actor identifies who is making a request. APIKeyID is set when the call
+// was authenticated with an API key acting as UserID.
+type Actor struct {
+	UserID   uuid.UUID
+	APIKeyID uuid.UUID
+}
+
+type auditContextKey int
+
+const (
+	actorContextKey auditContextKey = iota
+	requestIDContextKey
+)
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey, actor)
+}
+
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorContextKey).(Actor)
+	return actor, ok
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
+
+type AuditLog struct {
+	repo repository.AuditRepository
+}
+
+// NewAuditLog returns a log that logs write failures instead of failing the
+// change that was already committed.
+func NewAuditLog(repo repository.AuditRepository) *AuditLog {
+	return &AuditLog{repo: repo}
+}
+
+// Record appends an entry for a change that has already happened. before is
+// nil for creations and after is nil for deletions.
+func (a *AuditLog) Record(ctx context.Context, action, entityType, entityID string, before, after any) {
+	if a == nil {
+		return
+	}
+
+	entry := domain.AuditEntry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		RequestID:  RequestIDFromContext(ctx),
+	}
+	if actor, ok := ActorFromContext(ctx); ok {
+		if actor.UserID != uuid.Nil {
+			entry.ActorID = &actor.UserID
+		}
+		if actor.APIKeyID != uuid.Nil {
+			entry.APIKeyID = &actor.APIKeyID
+		}
+	}
+
+	var err error
+	if entry.Before, err = auditSnapshot(before); err == nil {
+		if entry.After, err = auditSnapshot(after); err == nil {
+			entry.Diff, err = auditDiff(entry.Before, entry.After)
+		}
+	}
+	if err == nil {
+		// The change is committed even if the client has gone away.
+		err = a.repo.Append(context.WithoutCancel(ctx), entry)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("audit log write failed",
+			zap.String("action", action),
+			zap.String("entityType", entityType),
+			zap.String("entityId", entityID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (a *AuditLog) List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		return nil, repository.ErrInvalid
+	}
+	if filter.Limit <= 0 || filter.Limit > maxAuditPage {
+		filter.Limit = maxAuditPage
+	}
+	return a.repo.List(ctx, filter)
+}
+
+func auditSnapshot(value any) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
+
+type auditChange struct {
+	From json.RawMessage `json:"from"`
+	To   json.RawMessage `json:"to"`
+}
+
+// auditDiff compares two snapshots field by field at the top level.
+func auditDiff(before, after json.RawMessage) (json.RawMessage, error) {
+	from, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]auditChange)
+	for key, value := range from {
+		if !bytes.Equal(value, to[key]) {
+			changes[key] = auditChange{From: value, To: nullIfEmpty(to[key])}
+		}
+	}
+	for key, value := range to {
+		if _, ok := from[key]; !ok {
+			changes[key] = auditChange{From: json.RawMessage("null"), To: value}
+		}
+	}
+	return json.Marshal(changes)
+}
+
+func auditFields(raw json.RawMessage) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if len(raw) == 0 {
+		return fields, nil
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range fields {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, value); err != nil {
+			return nil, err
+		}
+		fields[key] = compact.Bytes()
+	}
+	return fields, nil
+}
+
+func nullIfEmpty(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage("null")
+	}
+	return raw
+}
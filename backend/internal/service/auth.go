@@ -2,120 +2,602 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 	"islamdiplom/internal/domain"
+	"islamdiplom/internal/logging"
 	"islamdiplom/internal/repository"
 )
 
 type AuthService struct {
-	users     repository.UserRepository
-	jwtSecret []byte
-	ttl       time.Duration
+	users         repository.UserRepository
+	refreshTokens repository.RefreshTokenRepository
+	sessions      repository.SessionRepository
+	verification  *VerificationService
+	guard         *LoginGuard
+	hasher        *PasswordHasher
+	policy        *PasswordPolicy
+	mfa           *MFAService
+	keys          *TokenKeys
+	audit         *AuditLog
+	ttl           time.Duration
+	refreshTTL    time.Duration
 }
 
-func NewAuthService(users repository.UserRepository, secret string, ttl time.Duration) *AuthService {
-	return &AuthService{users: users, jwtSecret: []byte(secret), ttl: ttl}
+const (
+	tokenTypeAccess = "access"
+	tokenTypeMFA    = "mfa"
+	mfaChallengeTTL = 5 * time.Minute
+
+	maxUserAgentLength   = 512
+	sessionTouchInterval = time.Minute
+)
+
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
 }
 
-func (s *AuthService) Register(ctx context.Context, email, password string) (domain.User, string, error) {
-	email = strings.TrimSpace(strings.ToLower(email))
-	if email == "" || password == "" {
-		return domain.User{}, "", repository.ErrConflict
+// ClientInfo describes the device a session is opened from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+type AccessClaims struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+}
+
+// MFAChallengeError is returned by Login when the password was correct but
+// the account has a second factor. The token is exchanged for a TokenPair
+// via VerifyMFA together with a TOTP or recovery code.
+type MFAChallengeError struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+func (e *MFAChallengeError) Error() string {
+	return fmt.Sprintf("mfa required until %s", e.ExpiresAt.Format(time.RFC3339))
+}
+
+func NewAuthService(users repository.UserRepository, refreshTokens repository.RefreshTokenRepository, sessions repository.SessionRepository, verification *VerificationService, guard *LoginGuard, hasher *PasswordHasher, policy *PasswordPolicy, mfa *MFAService, keys *TokenKeys, audit *AuditLog, ttl, refreshTTL time.Duration) *AuthService {
+	return &AuthService{
+		users:         users,
+		refreshTokens: refreshTokens,
+		sessions:      sessions,
+		verification:  verification,
+		guard:         guard,
+		hasher:        hasher,
+		policy:        policy,
+		mfa:           mfa,
+		keys:          keys,
+		audit:         audit,
+		ttl:           ttl,
+		refreshTTL:    refreshTTL,
+	}
+}
+
+func (s *AuthService) Register(ctx context.Context, email, password string, client ClientInfo) (domain.User, TokenPair, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+	if err := s.policy.Validate(password, email); err != nil {
+		return domain.User{}, TokenPair{}, err
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := s.hasher.Hash(password)
 	if err != nil {
-		return domain.User{}, "", err
+		return domain.User{}, TokenPair{}, err
 	}
 
-	user := domain.User{Email: email, PasswordHash: string(hash)}
+	user := domain.User{Email: email, PasswordHash: hash}
 	created, err := s.users.Create(ctx, user)
 	if err != nil {
-		return domain.User{}, "", err
+		return domain.User{}, TokenPair{}, err
 	}
 
-	token, err := s.createToken(created.ID)
+	// A failed delivery must not fail the registration: the account already
+	// exists and the user can ask for another email.
+	if err := s.verification.Send(ctx, created); err != nil {
+		logging.FromContext(ctx).Warn("verification email failed", zap.String("userId", created.ID.String()), zap.Error(err))
+	}
+
+	tokens, err := s.issueTokens(ctx, created.ID, client)
 	if err != nil {
-		return domain.User{}, "", err
+		return domain.User{}, TokenPair{}, err
 	}
 
-	return created, token, nil
+	return created, tokens, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, email, password string) (domain.User, string, error) {
+func (s *AuthService) Login(ctx context.Context, email, password string, client ClientInfo) (domain.User, TokenPair, error) {
 	email = strings.TrimSpace(strings.ToLower(email))
 	if email == "" || password == "" {
-		return domain.User{}, "", repository.ErrUnauthorized
+		return domain.User{}, TokenPair{}, repository.ErrUnauthorized
+	}
+
+	if err := s.guard.Check(ctx, email, client.IP); err != nil {
+		return domain.User{}, TokenPair{}, err
 	}
 
 	user, err := s.users.GetByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
-			return domain.User{}, "", repository.ErrUnauthorized
+			s.hasher.VerifyDummy(password)
+			return domain.User{}, TokenPair{}, s.loginFailed(ctx, email, client.IP)
 		}
-		return domain.User{}, "", err
+		return domain.User{}, TokenPair{}, err
+	}
+	if user.PasswordHash == "" {
+		s.hasher.VerifyDummy(password)
+		return domain.User{}, TokenPair{}, s.loginFailed(ctx, email, client.IP)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return domain.User{}, "", repository.ErrUnauthorized
+	ok, rehash, err := s.hasher.Verify(user.PasswordHash, password)
+	if err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+	if !ok {
+		return domain.User{}, TokenPair{}, s.loginFailed(ctx, email, client.IP)
+	}
+
+	if rehash {
+		if hash, err := s.hasher.Hash(password); err == nil {
+			// Upgrading the hash is opportunistic; the next login retries it.
+			if err := s.users.UpdatePassword(ctx, user.ID, hash); err != nil {
+				logging.FromContext(ctx).Warn("password rehash failed", zap.String("userId", user.ID.String()), zap.Error(err))
+			}
+		}
 	}
 
-	token, err := s.createToken(user.ID)
+	tokens, err := s.completeLogin(ctx, user, client)
 	if err != nil {
-		return domain.User{}, "", err
+		return domain.User{}, TokenPair{}, err
 	}
 
-	return user, token, nil
+	return user, tokens, nil
 }
 
-func (s *AuthService) ParseToken(token string) (uuid.UUID, error) {
-	parsed, err := jwt.Parse(token, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, repository.ErrUnauthorized
+// completeLogin runs once the first factor is proven. Accounts with MFA get
+// a challenge instead of tokens; failures are cleared only after the second
+// factor, so guessing codes counts towards the same lock as passwords.
+func (s *AuthService) completeLogin(ctx context.Context, user domain.User, client ClientInfo) (TokenPair, error) {
+	enabled, err := s.mfa.Enabled(ctx, user.ID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if enabled {
+		token, expiresAt, err := s.signToken(user.ID, uuid.Nil, tokenTypeMFA, mfaChallengeTTL)
+		if err != nil {
+			return TokenPair{}, err
 		}
-		return s.jwtSecret, nil
-	})
-	if err != nil || !parsed.Valid {
-		return uuid.UUID{}, repository.ErrUnauthorized
+		return TokenPair{}, &MFAChallengeError{Token: token, ExpiresAt: expiresAt}
 	}
 
-	claims, ok := parsed.Claims.(jwt.MapClaims)
-	if !ok {
-		return uuid.UUID{}, repository.ErrUnauthorized
+	if err := s.guard.Succeed(ctx, user.Email); err != nil {
+		return TokenPair{}, err
+	}
+
+	return s.issueTokens(ctx, user.ID, client)
+}
+
+func (s *AuthService) VerifyMFA(ctx context.Context, mfaToken, code string, client ClientInfo) (domain.User, TokenPair, error) {
+	claims, err := s.parseToken(mfaToken, tokenTypeMFA)
+	if err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+
+	user, err := s.users.Get(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return domain.User{}, TokenPair{}, repository.ErrUnauthorized
+		}
+		return domain.User{}, TokenPair{}, err
+	}
+
+	if err := s.guard.Check(ctx, user.Email, client.IP); err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+	if err := s.mfa.Verify(ctx, user.ID, code); err != nil {
+		if errors.Is(err, repository.ErrUnauthorized) {
+			return domain.User{}, TokenPair{}, s.loginFailed(ctx, user.Email, client.IP)
+		}
+		return domain.User{}, TokenPair{}, err
+	}
+	if err := s.guard.Succeed(ctx, user.Email); err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+
+	tokens, err := s.issueTokens(ctx, user.ID, client)
+	if err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+
+	return user, tokens, nil
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already
+// rotated means it leaked, so the whole session is revoked.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string, client ClientInfo) (domain.User, TokenPair, error) {
+	if refreshToken == "" {
+		return domain.User{}, TokenPair{}, repository.ErrUnauthorized
+	}
+
+	current, err := s.refreshTokens.GetByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return domain.User{}, TokenPair{}, repository.ErrUnauthorized
+		}
+		return domain.User{}, TokenPair{}, err
+	}
+	if current.RevokedAt != nil {
+		if err := s.revokeSession(ctx, current.UserID, current.FamilyID); err != nil {
+			return domain.User{}, TokenPair{}, err
+		}
+		return domain.User{}, TokenPair{}, repository.ErrUnauthorized
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return domain.User{}, TokenPair{}, repository.ErrUnauthorized
+	}
+
+	session, err := s.sessions.Get(ctx, current.FamilyID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return domain.User{}, TokenPair{}, err
+	}
+	if err != nil || session.RevokedAt != nil {
+		if err := s.refreshTokens.RevokeFamily(ctx, current.FamilyID); err != nil {
+			return domain.User{}, TokenPair{}, err
+		}
+		return domain.User{}, TokenPair{}, repository.ErrUnauthorized
+	}
+
+	user, err := s.users.Get(ctx, current.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return domain.User{}, TokenPair{}, repository.ErrUnauthorized
+		}
+		return domain.User{}, TokenPair{}, err
+	}
+
+	raw, next, err := s.newRefreshToken(user.ID, current.FamilyID)
+	if err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+	next, err = s.refreshTokens.Rotate(ctx, current.ID, next)
+	if err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			if err := s.revokeSession(ctx, current.UserID, current.FamilyID); err != nil {
+				return domain.User{}, TokenPair{}, err
+			}
+			return domain.User{}, TokenPair{}, repository.ErrUnauthorized
+		}
+		return domain.User{}, TokenPair{}, err
+	}
+
+	if err := s.sessions.Touch(ctx, session.ID, client.IP, time.Now().UTC()); err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+
+	access, accessExpiresAt, err := s.createToken(user.ID, session.ID)
+	if err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+
+	return user, TokenPair{
+		AccessToken:      access,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     raw,
+		RefreshExpiresAt: next.ExpiresAt,
+	}, nil
+}
+
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return nil
+	}
+	current, err := s.refreshTokens.GetByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.revokeSession(ctx, current.UserID, current.FamilyID)
+}
+
+func (s *AuthService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	if err := s.revokeAllSessions(ctx, userID); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditRevokeSessions, domain.AuditEntityUser, userID.String(), nil, nil)
+	return nil
+}
+
+// Sessions lists the user's signed-in devices, marking the caller's own.
+func (s *AuthService) Sessions(ctx context.Context, userID, currentID uuid.UUID) ([]domain.Session, error) {
+	sessions, err := s.sessions.ListActive(ctx, userID, time.Now().Add(-s.refreshTTL).UTC())
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if err := s.sessions.Revoke(ctx, sessionID, userID, time.Now().UTC()); err != nil {
+		return err
+	}
+	if err := s.refreshTokens.RevokeFamily(ctx, sessionID); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditRevoke, domain.AuditEntitySession, sessionID.String(), nil, nil)
+	return nil
+}
+
+// Authenticate checks an access token and that its session is still open,
+// so revoking a session cuts off its access tokens before they expire.
+func (s *AuthService) Authenticate(ctx context.Context, token, clientIP string) (AccessClaims, error) {
+	claims, err := s.ParseToken(token)
+	if err != nil {
+		return AccessClaims{}, err
+	}
+	if claims.SessionID == uuid.Nil {
+		return AccessClaims{}, repository.ErrUnauthorized
+	}
+
+	session, err := s.sessions.Get(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return AccessClaims{}, repository.ErrUnauthorized
+		}
+		return AccessClaims{}, err
+	}
+	if session.RevokedAt != nil || session.UserID != claims.UserID {
+		return AccessClaims{}, repository.ErrUnauthorized
+	}
+
+	now := time.Now().UTC()
+	if now.Sub(session.LastActiveAt) > sessionTouchInterval {
+		// Activity tracking must not fail the request it describes.
+		if err := s.sessions.Touch(ctx, session.ID, clientIP, now); err != nil {
+			logging.FromContext(ctx).Warn("session touch failed", zap.String("sessionId", session.ID.String()), zap.Error(err))
+		}
+	}
+	return claims, nil
+}
+
+func (s *AuthService) ParseToken(token string) (AccessClaims, error) {
+	return s.parseToken(token, tokenTypeAccess)
+}
+
+func (s *AuthService) parseToken(token, tokenType string) (AccessClaims, error) {
+	claims, err := s.keys.Verify(token)
+	if err != nil {
+		return AccessClaims{}, err
+	}
+	if typ, _ := claims["typ"].(string); typ != tokenType {
+		return AccessClaims{}, repository.ErrUnauthorized
 	}
 
 	sub, ok := claims["sub"].(string)
 	if !ok {
-		return uuid.UUID{}, repository.ErrUnauthorized
+		return AccessClaims{}, repository.ErrUnauthorized
 	}
 
 	id, err := uuid.Parse(sub)
 	if err != nil {
-		return uuid.UUID{}, repository.ErrUnauthorized
+		return AccessClaims{}, repository.ErrUnauthorized
 	}
 
-	return id, nil
+	result := AccessClaims{UserID: id}
+	if sid, ok := claims["sid"].(string); ok {
+		result.SessionID, err = uuid.Parse(sid)
+		if err != nil {
+			return AccessClaims{}, repository.ErrUnauthorized
+		}
+	}
+	return result, nil
+}
+
+func (s *AuthService) JWKS() []JWK {
+	return s.keys.JWKS()
 }
 
 func (s *AuthService) GetUser(ctx context.Context, id uuid.UUID) (domain.User, error) {
 	return s.users.Get(ctx, id)
 }
 
-func (s *AuthService) createToken(userID uuid.UUID) (string, error) {
+// SetRole changes a user's role. Admins grant organizer and admin roles
+// through the admin API once the first admin exists; see GrantAdmins.
+func (s *AuthService) SetRole(ctx context.Context, userID uuid.UUID, role string) (domain.User, error) {
+	if !slices.Contains(domain.Roles, role) {
+		return domain.User{}, repository.Invalid("role", "invalid", "expected one of "+strings.Join(domain.Roles, ", "))
+	}
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+	if err := s.users.UpdateRole(ctx, userID, role); err != nil {
+		return domain.User{}, err
+	}
+	s.audit.Record(ctx, domain.AuditUpdate, domain.AuditEntityUser, userID.String(),
+		map[string]string{"role": user.Role},
+		map[string]string{"role": role})
+	user.Role = role
+	return user, nil
+}
+
+// GrantAdmins makes the accounts behind emails admins. It runs at startup
+// from ADMIN_EMAILS and is the only way to create the first admin. Emails
+// without an account are skipped and picked up on a later start.
+func (s *AuthService) GrantAdmins(ctx context.Context, emails []string) error {
+	for _, raw := range emails {
+		email, err := normalizeEmail(raw)
+		if err != nil {
+			return fmt.Errorf("admin email %q: %w", raw, err)
+		}
+		user, err := s.users.GetByEmail(ctx, email)
+		if errors.Is(err, repository.ErrNotFound) {
+			logging.FromContext(ctx).Warn("admin email has no account yet", zap.String("email", email))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := s.SetRole(ctx, user.ID, domain.RoleAdmin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnforceMFA rejects privileged users who have not enrolled a second factor
+// while the policy requires one for their role.
+func (s *AuthService) EnforceMFA(ctx context.Context, user domain.User) error {
+	return s.mfa.Enforce(ctx, user)
+}
+
+func (s *AuthService) loginFailed(ctx context.Context, email, clientIP string) error {
+	if err := s.guard.Fail(ctx, email, clientIP); err != nil {
+		return err
+	}
+	return repository.ErrUnauthorized
+}
+
+// revokeSession closes a session and its refresh token family. Sessions
+// revoked earlier are not an error.
+func (s *AuthService) revokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if err := s.sessions.Revoke(ctx, sessionID, userID, time.Now().UTC()); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	return s.refreshTokens.RevokeFamily(ctx, sessionID)
+}
+
+// dropCredentials signs everyone out of an account and removes its password
+// and second factor, leaving only the external identity about to be linked.
+func (s *AuthService) dropCredentials(ctx context.Context, userID uuid.UUID) error {
+	if err := s.users.UpdatePassword(ctx, userID, ""); err != nil {
+		return err
+	}
+	if err := s.mfa.mfa.Disable(ctx, userID); err != nil {
+		return err
+	}
+	return s.revokeAllSessions(ctx, userID)
+}
+
+func (s *AuthService) revokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	if err := s.sessions.RevokeAllForUser(ctx, userID, time.Now().UTC()); err != nil {
+		return err
+	}
+	return s.refreshTokens.RevokeAllForUser(ctx, userID)
+}
+
+// issueTokens opens a new session; its ID doubles as the refresh family.
+func (s *AuthService) issueTokens(ctx context.Context, userID uuid.UUID, client ClientInfo) (TokenPair, error) {
+	userAgent := client.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+	session, err := s.sessions.Create(ctx, domain.Session{
+		ID:        uuid.New(),
+		UserID:    userID,
+		UserAgent: userAgent,
+		IP:        client.IP,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	access, accessExpiresAt, err := s.createToken(userID, session.ID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	raw, refresh, err := s.newRefreshToken(userID, session.ID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err = s.refreshTokens.Create(ctx, refresh)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:      access,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     raw,
+		RefreshExpiresAt: refresh.ExpiresAt,
+	}, nil
+}
+
+func (s *AuthService) createToken(userID, sessionID uuid.UUID) (string, time.Time, error) {
+	return s.signToken(userID, sessionID, tokenTypeAccess, s.ttl)
+}
+
+func (s *AuthService) signToken(userID, sessionID uuid.UUID, tokenType string, ttl time.Duration) (string, time.Time, error) {
 	now := time.Now()
+	expiresAt := now.Add(ttl)
 	claims := jwt.MapClaims{
 		"sub": userID.String(),
+		"typ": tokenType,
 		"iat": now.Unix(),
-		"exp": now.Add(s.ttl).Unix(),
+		"exp": expiresAt.Unix(),
+	}
+	if sessionID != uuid.Nil {
+		claims["sid"] = sessionID.String()
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.jwtSecret)
+	signed, err := s.keys.Sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+func (s *AuthService) newRefreshToken(userID, familyID uuid.UUID) (string, domain.RefreshToken, error) {
+	raw, err := randomToken()
+	if err != nil {
+		return "", domain.RefreshToken{}, err
+	}
+	return raw, domain.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(s.refreshTTL).UTC(),
+	}, nil
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	users := &fakeUsers{byID: map[uuid.UUID]domain.User{userID: {ID: userID, Email: "user@example.com", Role: domain.RoleUser}}}
+	tokens := &memRefreshTokens{byID: map[uuid.UUID]domain.RefreshToken{}}
+	sessions := &memSessions{byID: map[uuid.UUID]domain.Session{}}
+	auth := NewAuthService(users, tokens, sessions, nil, nil, nil, nil, nil, NewHMACTokenKeys("test-secret"), nil, time.Minute, time.Hour)
+	client := ClientInfo{IP: "192.0.2.1"}
+
+	stolen, err := auth.issueTokens(ctx, userID, client)
+	if err != nil {
+		t.Fatalf("issueTokens: %v", err)
+	}
+	other, err := auth.issueTokens(ctx, userID, client)
+	if err != nil {
+		t.Fatalf("issueTokens: %v", err)
+	}
+
+	_, rotated, err := auth.Refresh(ctx, stolen.RefreshToken, client)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	// Presenting the rotated-out token again means it leaked.
+	if _, _, err := auth.Refresh(ctx, stolen.RefreshToken, client); !errors.Is(err, repository.ErrUnauthorized) {
+		t.Fatalf("reused Refresh = %v, want ErrUnauthorized", err)
+	}
+	if _, _, err := auth.Refresh(ctx, rotated.RefreshToken, client); !errors.Is(err, repository.ErrUnauthorized) {
+		t.Fatalf("Refresh with the latest token = %v, want ErrUnauthorized after reuse", err)
+	}
+
+	family := tokens.familyOf(stolen.RefreshToken)
+	for _, token := range tokens.byID {
+		if token.FamilyID == family && token.RevokedAt == nil {
+			t.Fatalf("token %s in the reused family is still active", token.ID)
+		}
+	}
+	if session := sessions.byID[family]; session.RevokedAt == nil {
+		t.Fatal("the session of the reused family is still active")
+	}
+
+	// Other sessions of the same user are left alone.
+	if _, _, err := auth.Refresh(ctx, other.RefreshToken, client); err != nil {
+		t.Fatalf("Refresh of another session: %v", err)
+	}
+}
+
+type memRefreshTokens struct {
+	byID map[uuid.UUID]domain.RefreshToken
+}
+
+func (r *memRefreshTokens) familyOf(raw string) uuid.UUID {
+	token, _ := r.GetByHash(context.Background(), hashToken(raw))
+	return token.FamilyID
+}
+
+func (r *memRefreshTokens) Create(_ context.Context, token domain.RefreshToken) (domain.RefreshToken, error) {
+	token.ID = uuid.New()
+	token.CreatedAt = time.Now().UTC()
+	r.byID[token.ID] = token
+	return token, nil
+}
+
+func (r *memRefreshTokens) GetByHash(_ context.Context, hash string) (domain.RefreshToken, error) {
+	for _, token := range r.byID {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return domain.RefreshToken{}, repository.ErrNotFound
+}
+
+func (r *memRefreshTokens) Rotate(ctx context.Context, currentID uuid.UUID, next domain.RefreshToken) (domain.RefreshToken, error) {
+	current := r.byID[currentID]
+	if current.RevokedAt != nil {
+		return domain.RefreshToken{}, repository.ErrConflict
+	}
+	created, _ := r.Create(ctx, next)
+	now := time.Now().UTC()
+	current.RevokedAt = &now
+	current.ReplacedBy = &created.ID
+	r.byID[currentID] = current
+	return created, nil
+}
+
+func (r *memRefreshTokens) RevokeFamily(_ context.Context, familyID uuid.UUID) error {
+	now := time.Now().UTC()
+	for id, token := range r.byID {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			r.byID[id] = token
+		}
+	}
+	return nil
+}
+
+func (r *memRefreshTokens) RevokeAllForUser(_ context.Context, userID uuid.UUID) error {
+	now := time.Now().UTC()
+	for id, token := range r.byID {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			r.byID[id] = token
+		}
+	}
+	return nil
+}
+
+type memSessions struct {
+	repository.SessionRepository
+	byID map[uuid.UUID]domain.Session
+}
+
+func (r *memSessions) Create(_ context.Context, session domain.Session) (domain.Session, error) {
+	r.byID[session.ID] = session
+	return session, nil
+}
+
+func (r *memSessions) Get(_ context.Context, id uuid.UUID) (domain.Session, error) {
+	session, ok := r.byID[id]
+	if !ok {
+		return domain.Session{}, repository.ErrNotFound
+	}
+	return session, nil
+}
+
+func (r *memSessions) Touch(_ context.Context, id uuid.UUID, ip string, at time.Time) error {
+	session := r.byID[id]
+	session.IP = ip
+	session.LastActiveAt = at
+	r.byID[id] = session
+	return nil
+}
+
+func (r *memSessions) Revoke(_ context.Context, id, userID uuid.UUID, at time.Time) error {
+	session, ok := r.byID[id]
+	if !ok || session.UserID != userID {
+		return repository.ErrNotFound
+	}
+	session.RevokedAt = &at
+	r.byID[id] = session
+	return nil
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/google/uuid"
 	"islamdiplom/internal/domain"
@@ -13,15 +14,20 @@ const (
 	bookingStatusCanceled = "canceled"
 	defaultCurrency       = "KZT"
 	defaultSeatPrice      = 2500
+	maxBookingMetadata    = 4 << 10
 )
 
 type BookingService struct {
-	repo      repository.BookingRepository
-	events    repository.EventRepository
+	repo            repository.BookingRepository
+	events          repository.EventRepository
+	halls           repository.HallRepository
+	users           repository.UserRepository
+	audit           *AuditLog
+	requireVerified bool
 }
 
-func NewBookingService(repo repository.BookingRepository, events repository.EventRepository) *BookingService {
-	return &BookingService{repo: repo, events: events}
+func NewBookingService(repo repository.BookingRepository, events repository.EventRepository, halls repository.HallRepository, users repository.UserRepository, audit *AuditLog, requireVerified bool) *BookingService {
+	return &BookingService{repo: repo, events: events, halls: halls, users: users, audit: audit, requireVerified: requireVerified}
 }
 
 func (s *BookingService) List(ctx context.Context, userID uuid.UUID) ([]domain.Booking, error) {
@@ -30,10 +36,24 @@ func (s *BookingService) List(ctx context.Context, userID uuid.UUID) ([]domain.B
 
 func (s *BookingService) Create(ctx context.Context, userID uuid.UUID, eventID uuid.UUID, seats []string) (domain.Booking, error) {
 	if len(seats) == 0 {
-		return domain.Booking{}, repository.ErrConflict
+		return domain.Booking{}, repository.Invalid("seats", "required", "")
 	}
 
-	if _, err := s.events.Get(ctx, eventID); err != nil {
+	if s.requireVerified {
+		user, err := s.users.Get(ctx, userID)
+		if err != nil {
+			return domain.Booking{}, err
+		}
+		if user.EmailVerifiedAt == nil {
+			return domain.Booking{}, repository.ErrForbidden
+		}
+	}
+
+	event, err := s.events.Get(ctx, eventID)
+	if err != nil {
+		return domain.Booking{}, err
+	}
+	if err := s.ensureSeats(ctx, event, seats); err != nil {
 		return domain.Booking{}, err
 	}
 
@@ -46,13 +66,100 @@ func (s *BookingService) Create(ctx context.Context, userID uuid.UUID, eventID u
 		Seats:      seats,
 	}
 
-	return s.repo.Create(ctx, booking)
+	created, err := s.repo.Create(ctx, booking)
+	if err != nil {
+		return domain.Booking{}, err
+	}
+	s.audit.Record(ctx, domain.AuditCreate, domain.AuditEntityBooking, created.ID.String(), nil, bookingSnapshot(created))
+	return created, nil
+}
+
+func (s *BookingService) Get(ctx context.Context, id uuid.UUID, userID uuid.UUID) (domain.Booking, error) {
+	return s.repo.Get(ctx, id, userID)
+}
+
+// UpdateMetadata replaces the metadata of one of userID's bookings. It must
+// be a JSON object of at most maxBookingMetadata bytes.
+func (s *BookingService) UpdateMetadata(ctx context.Context, id uuid.UUID, userID uuid.UUID, metadata json.RawMessage) (domain.Booking, error) {
+	var object map[string]json.RawMessage
+	if len(metadata) > maxBookingMetadata || json.Unmarshal(metadata, &object) != nil || object == nil {
+		return domain.Booking{}, repository.Invalid("metadata", "invalid", "expected a JSON object of at most 4 KiB")
+	}
+
+	before, err := s.repo.Get(ctx, id, userID)
+	if err != nil {
+		return domain.Booking{}, err
+	}
+	updated, err := s.repo.UpdateMetadata(ctx, id, userID, metadata)
+	if err != nil {
+		return domain.Booking{}, err
+	}
+	s.audit.Record(ctx, domain.AuditUpdate, domain.AuditEntityBooking, id.String(), bookingSnapshot(before), bookingSnapshot(updated))
+	return updated, nil
 }
 
 func (s *BookingService) Cancel(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
-	return s.repo.Cancel(ctx, id, userID)
+	if err := s.repo.Cancel(ctx, id, userID); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditCancel, domain.AuditEntityBooking, id.String(),
+		map[string]string{"status": bookingStatusActive},
+		map[string]string{"status": bookingStatusCanceled})
+	return nil
+}
+
+// auditedBooking replaces the client-defined metadata, which may hold
+// personal notes, with its hash: the audit log is append-only and could not
+// erase it later. The hash still shows when and by whom metadata changed.
+type auditedBooking struct {
+	domain.Booking
+	Metadata string `json:"metadata,omitempty"`
+}
+
+func bookingSnapshot(booking domain.Booking) auditedBooking {
+	snapshot := auditedBooking{Booking: booking}
+	if len(booking.Metadata) > 0 {
+		snapshot.Metadata = hashToken(string(booking.Metadata))
+	}
+	return snapshot
 }
 
 func (s *BookingService) ListSeatsByEvent(ctx context.Context, eventID uuid.UUID) ([]string, error) {
 	return s.repo.ListSeatsByEvent(ctx, eventID)
 }
+
+func (s *BookingService) ensureSeats(ctx context.Context, event domain.Event, seats []string) error {
+	hall, err := s.halls.Get(ctx, event.HallID)
+	if err != nil {
+		return err
+	}
+	if len(hall.Layout) > 0 {
+		for _, seat := range seats {
+			if !hallHasSeat(hall, seat) {
+				return repository.Invalid("seats", "unknown_seat", "the hall has no seat "+seat)
+			}
+		}
+	}
+
+	occupied, err := s.repo.ListSeatsByEvent(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	taken := make(map[string]bool, len(occupied))
+	for _, seat := range occupied {
+		taken[seat] = true
+	}
+	var conflicts []string
+	for _, seat := range seats {
+		if taken[seat] {
+			conflicts = append(conflicts, seat)
+		}
+	}
+	if len(conflicts) > 0 {
+		return &repository.SeatsTakenError{Seats: conflicts}
+	}
+	if available := hall.Capacity - len(occupied); len(seats) > available {
+		return &repository.CapacityError{Available: max(available, 0)}
+	}
+	return nil
+}
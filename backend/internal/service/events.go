@@ -3,45 +3,149 @@ package service
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"islamdiplom/internal/domain"
 	"islamdiplom/internal/repository"
 )
 
+var errUnknownVenue = repository.Invalid("venueId", "not_found", "")
+
 type EventService struct {
-	repo   repository.EventRepository
-	venues repository.VenueRepository
+	repo          repository.EventRepository
+	venues        repository.VenueRepository
+	halls         repository.HallRepository
+	audit         *AuditLog
+	setupBuffer   time.Duration
+	cleanupBuffer time.Duration
 }
 
-func NewEventService(repo repository.EventRepository, venues repository.VenueRepository) *EventService {
-	return &EventService{repo: repo, venues: venues}
+func NewEventService(repo repository.EventRepository, venues repository.VenueRepository, halls repository.HallRepository, audit *AuditLog, setupBuffer, cleanupBuffer time.Duration) *EventService {
+	return &EventService{repo: repo, venues: venues, halls: halls, audit: audit, setupBuffer: setupBuffer, cleanupBuffer: cleanupBuffer}
 }
 
 func (s *EventService) List(ctx context.Context) ([]domain.Event, error) {
-	return s.repo.List(ctx)
+	events, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	zones, err := s.venueZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		events[i] = localizeEvent(events[i], zones.lookup(events[i].VenueID))
+	}
+	return events, nil
+}
+
+func (s *EventService) ListNear(ctx context.Context, lat, lng, radiusKm float64) ([]domain.NearbyEvent, error) {
+	if !(lat >= -90 && lat <= 90) || !(lng >= -180 && lng <= 180) {
+		return nil, repository.Invalid("near", "out_of_range", "coordinates are out of range")
+	}
+	if !(radiusKm > 0) {
+		return nil, repository.Invalid("radiusKm", "out_of_range", "radius must be positive")
+	}
+	events, err := s.repo.ListNear(ctx, lat, lng, radiusKm, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	zones, err := s.venueZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		events[i].Event = localizeEvent(events[i].Event, zones.lookup(events[i].VenueID))
+	}
+	return events, nil
 }
 
 func (s *EventService) Get(ctx context.Context, id uuid.UUID) (domain.Event, error) {
-	return s.repo.Get(ctx, id)
+	event, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return domain.Event{}, err
+	}
+	return s.localize(ctx, event)
+}
+
+func (s *EventService) ParseTimes(ctx context.Context, venueID uuid.UUID, startAt, endAt string) (time.Time, time.Time, error) {
+	loc, err := s.venueZone(ctx, venueID)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	start, err := ParseEventTime(startAt, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, repository.Invalid("startAt", "invalid_time", "expected RFC 3339 or venue wall-clock time")
+	}
+	end, err := ParseEventTime(endAt, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, repository.Invalid("endAt", "invalid_time", "expected RFC 3339 or venue wall-clock time")
+	}
+	return start, end, nil
 }
 
 func (s *EventService) Create(ctx context.Context, event domain.Event) (domain.Event, error) {
-	if err := s.ensureVenue(ctx, event.VenueID); err != nil {
+	event, err := s.validate(ctx, event)
+	if err != nil {
 		return domain.Event{}, err
 	}
-	return s.repo.Create(ctx, event)
+	created, err := s.repo.Create(ctx, event)
+	if err != nil {
+		return domain.Event{}, err
+	}
+	s.audit.Record(ctx, domain.AuditCreate, domain.AuditEntityEvent, created.ID.String(), nil, created)
+	return s.localize(ctx, created)
 }
 
 func (s *EventService) Update(ctx context.Context, event domain.Event) (domain.Event, error) {
-	if err := s.ensureVenue(ctx, event.VenueID); err != nil {
+	before, err := s.repo.Get(ctx, event.ID)
+	if err != nil {
+		return domain.Event{}, err
+	}
+	if event.Version != 0 && event.Version != before.Version {
+		return domain.Event{}, repository.ErrPreconditionFailed
+	}
+	event, err = s.validate(ctx, event)
+	if err != nil {
 		return domain.Event{}, err
 	}
-	return s.repo.Update(ctx, event)
+	updated, err := s.repo.Update(ctx, event)
+	if err != nil {
+		return domain.Event{}, err
+	}
+	s.audit.Record(ctx, domain.AuditUpdate, domain.AuditEntityEvent, updated.ID.String(), before, updated)
+	return s.localize(ctx, updated)
 }
 
 func (s *EventService) Delete(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Delete(ctx, id)
+	before, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditDelete, domain.AuditEntityEvent, id.String(), before, nil)
+	return nil
+}
+
+func (s *EventService) validate(ctx context.Context, event domain.Event) (domain.Event, error) {
+	if !event.EndAt.After(event.StartAt) {
+		return domain.Event{}, repository.Invalid("endAt", "before_start", "endAt must be after startAt")
+	}
+	if err := s.ensureVenue(ctx, event.VenueID); err != nil {
+		return domain.Event{}, err
+	}
+	hallID, err := s.resolveHall(ctx, event.VenueID, event.HallID)
+	if err != nil {
+		return domain.Event{}, err
+	}
+	event.HallID = hallID
+	if err := s.ensureNoOverlap(ctx, event); err != nil {
+		return domain.Event{}, err
+	}
+	return event, nil
 }
 
 func (s *EventService) ensureVenue(ctx context.Context, venueID uuid.UUID) error {
@@ -49,14 +153,100 @@ func (s *EventService) ensureVenue(ctx context.Context, venueID uuid.UUID) error
 		return nil
 	}
 	if venueID == uuid.Nil {
-		return repository.ErrInvalid
+		return repository.Invalid("venueId", "required", "")
 	}
 	_, err := s.venues.Get(ctx, venueID)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
-			return repository.ErrInvalid
+			return errUnknownVenue
 		}
 		return err
 	}
 	return nil
 }
+
+type zoneMap map[uuid.UUID]*time.Location
+
+func (z zoneMap) lookup(venueID uuid.UUID) *time.Location {
+	if loc, ok := z[venueID]; ok {
+		return loc
+	}
+	return time.UTC
+}
+
+func (s *EventService) venueZones(ctx context.Context) (zoneMap, error) {
+	zones := make(zoneMap)
+	if s.venues == nil {
+		return zones, nil
+	}
+	venues, err := s.venues.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, venue := range venues {
+		zones[venue.ID] = loadLocation(venue.Timezone)
+	}
+	return zones, nil
+}
+
+func (s *EventService) venueZone(ctx context.Context, venueID uuid.UUID) (*time.Location, error) {
+	if s.venues == nil {
+		return time.UTC, nil
+	}
+	venue, err := s.venues.Get(ctx, venueID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errUnknownVenue
+		}
+		return nil, err
+	}
+	return loadLocation(venue.Timezone), nil
+}
+
+func (s *EventService) localize(ctx context.Context, event domain.Event) (domain.Event, error) {
+	loc, err := s.venueZone(ctx, event.VenueID)
+	if err != nil {
+		return domain.Event{}, err
+	}
+	return localizeEvent(event, loc), nil
+}
+
+func (s *EventService) resolveHall(ctx context.Context, venueID, hallID uuid.UUID) (uuid.UUID, error) {
+	if hallID == uuid.Nil {
+		halls, err := s.halls.ListByVenue(ctx, venueID)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if len(halls) == 0 {
+			return uuid.Nil, repository.Invalid("hallId", "required", "the venue has no halls")
+		}
+		return halls[0].ID, nil
+	}
+
+	hall, err := s.halls.Get(ctx, hallID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return uuid.Nil, repository.Invalid("hallId", "not_found", "")
+		}
+		return uuid.Nil, err
+	}
+	if hall.VenueID != venueID {
+		return uuid.Nil, repository.Invalid("hallId", "wrong_venue", "the hall belongs to another venue")
+	}
+	return hall.ID, nil
+}
+
+// Both events need their setup and cleanup time, so the search window is
+// widened by the sum of the buffers on each side. The database constraint
+// only backs up the raw overlap, not the buffers.
+func (s *EventService) ensureNoOverlap(ctx context.Context, event domain.Event) error {
+	margin := s.setupBuffer + s.cleanupBuffer
+	clashes, err := s.repo.ListOverlapping(ctx, event.HallID, event.StartAt.Add(-margin), event.EndAt.Add(margin), event.ID)
+	if err != nil {
+		return err
+	}
+	if len(clashes) > 0 {
+		return &repository.OverlapError{EventID: clashes[0].ID}
+	}
+	return nil
+}
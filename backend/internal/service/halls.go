@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+const defaultHallName = "Основной зал"
+
+type HallService struct {
+	repo   repository.HallRepository
+	venues repository.VenueRepository
+	audit  *AuditLog
+}
+
+func NewHallService(repo repository.HallRepository, venues repository.VenueRepository, audit *AuditLog) *HallService {
+	return &HallService{repo: repo, venues: venues, audit: audit}
+}
+
+func (s *HallService) List(ctx context.Context, venueID uuid.UUID) ([]domain.Hall, error) {
+	if _, err := s.venues.Get(ctx, venueID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByVenue(ctx, venueID)
+}
+
+func (s *HallService) Get(ctx context.Context, venueID, id uuid.UUID) (domain.Hall, error) {
+	hall, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return domain.Hall{}, err
+	}
+	if hall.VenueID != venueID {
+		return domain.Hall{}, repository.ErrNotFound
+	}
+	return hall, nil
+}
+
+func (s *HallService) Create(ctx context.Context, hall domain.Hall) (domain.Hall, error) {
+	if _, err := s.venues.Get(ctx, hall.VenueID); err != nil {
+		return domain.Hall{}, err
+	}
+	hall, err := normalizeHall(hall)
+	if err != nil {
+		return domain.Hall{}, err
+	}
+	created, err := s.repo.Create(ctx, hall)
+	if err != nil {
+		return domain.Hall{}, err
+	}
+	s.audit.Record(ctx, domain.AuditCreate, domain.AuditEntityHall, created.ID.String(), nil, created)
+	return created, nil
+}
+
+func (s *HallService) Update(ctx context.Context, hall domain.Hall) (domain.Hall, error) {
+	before, err := s.Get(ctx, hall.VenueID, hall.ID)
+	if err != nil {
+		return domain.Hall{}, err
+	}
+	hall, err = normalizeHall(hall)
+	if err != nil {
+		return domain.Hall{}, err
+	}
+	updated, err := s.repo.Update(ctx, hall)
+	if err != nil {
+		return domain.Hall{}, err
+	}
+	s.audit.Record(ctx, domain.AuditUpdate, domain.AuditEntityHall, updated.ID.String(), before, updated)
+	return updated, nil
+}
+
+func (s *HallService) Delete(ctx context.Context, venueID, id uuid.UUID) error {
+	before, err := s.Get(ctx, venueID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditDelete, domain.AuditEntityHall, id.String(), before, nil)
+	return nil
+}
+
+func normalizeHall(hall domain.Hall) (domain.Hall, error) {
+	hall.Name = strings.TrimSpace(hall.Name)
+	if hall.Name == "" {
+		return domain.Hall{}, repository.Invalid("name", "required", "")
+	}
+
+	seen := make(map[string]struct{}, len(hall.Layout))
+	total := 0
+	for _, row := range hall.Layout {
+		if row.Label == "" || row.Seats <= 0 {
+			return domain.Hall{}, repository.Invalid("layout", "invalid_row", "rows need a label and a positive seat count")
+		}
+		if _, ok := seen[row.Label]; ok {
+			return domain.Hall{}, repository.Invalid("layout", "duplicate_row", "row "+row.Label+" is listed twice")
+		}
+		seen[row.Label] = struct{}{}
+		total += row.Seats
+	}
+
+	if hall.Capacity == 0 {
+		hall.Capacity = total
+	}
+	if hall.Capacity <= 0 || hall.Capacity < total {
+		return domain.Hall{}, repository.Invalid("capacity", "below_layout", "capacity must cover every seat in the layout")
+	}
+	return hall, nil
+}
+
+func defaultHall(venueID uuid.UUID) domain.Hall {
+	layout := make([]domain.SeatRow, 0, 6)
+	for _, label := range []string{"A", "B", "C", "D", "E", "F"} {
+		layout = append(layout, domain.SeatRow{Label: label, Seats: 10})
+	}
+	return domain.Hall{VenueID: venueID, Name: defaultHallName, Capacity: 60, Layout: layout}
+}
+
+func hallHasSeat(hall domain.Hall, label string) bool {
+	row, number, ok := strings.Cut(label, "-")
+	if !ok {
+		return false
+	}
+	seat, err := strconv.Atoi(number)
+	if err != nil {
+		return false
+	}
+	for _, layoutRow := range hall.Layout {
+		if layoutRow.Label == row {
+			return seat >= 1 && seat <= layoutRow.Seats
+		}
+	}
+	return false
+}
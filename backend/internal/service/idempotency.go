@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+const (
+	maxIdempotencyKeyLen = 255
+	// idempotencyLease outlasts the server write timeout, so a reservation
+	// is only taken over once its request cannot still be running.
+	idempotencyLease = time.Minute
+)
+
+// ErrIdempotencyKeyReused means a key was sent again with a different
+// request than the one it was first used for.
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+
+type IdempotencyService struct {
+	repo repository.IdempotencyRepository
+	ttl  time.Duration
+}
+
+func NewIdempotencyService(repo repository.IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{repo: repo, ttl: ttl}
+}
+
+// Begin claims key for subject. When the key was already used for the same
+// request and that request has finished, the stored response is returned
+// with replay set to true. A request still in flight yields ErrConflict; one
+// that never finished is handed to the retry once its lease has run out.
+func (s *IdempotencyService) Begin(ctx context.Context, subject, key, fingerprint string) (domain.IdempotencyRecord, bool, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLen {
+		return domain.IdempotencyRecord{}, false, repository.ErrInvalid
+	}
+
+	now := time.Now().UTC()
+	record, created, err := s.repo.Reserve(ctx, domain.IdempotencyRecord{
+		Subject:     subject,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+		LockedUntil: now.Add(idempotencyLease),
+	})
+	if err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	}
+	if created {
+		return record, false, nil
+	}
+	if record.Fingerprint != fingerprint {
+		return domain.IdempotencyRecord{}, false, ErrIdempotencyKeyReused
+	}
+	if !record.Completed() {
+		return domain.IdempotencyRecord{}, false, repository.ErrConflict
+	}
+	return record, true, nil
+}
+
+// Complete stores the response for replay. The handler has already run, so
+// this must not depend on the client still waiting.
+func (s *IdempotencyService) Complete(ctx context.Context, subject, key string, statusCode int, contentType string, body []byte) error {
+	return s.repo.Complete(context.WithoutCancel(ctx), subject, key, statusCode, contentType, body)
+}
+
+// Release forgets a key whose request failed so the client can retry it.
+func (s *IdempotencyService) Release(ctx context.Context, subject, key string) error {
+	return s.repo.Release(context.WithoutCancel(ctx), subject, key)
+}
+
+func (s *IdempotencyService) Prune(ctx context.Context) error {
+	return s.repo.Prune(ctx, time.Now().UTC())
+}
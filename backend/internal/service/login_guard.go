@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+const maxLoginAttemptsPage = 200
+
+type LoginPolicy struct {
+	MaxEmailFailures int
+	MaxIPFailures    int
+	Window           time.Duration
+}
+
+// LoginGuard locks an email after too many failed logins within a sliding
+// window and throttles client IPs that try many emails. The email lock lifts
+// on its own once old failures leave the window, or when an admin unlocks it.
+type LoginGuard struct {
+	attempts repository.LoginAttemptRepository
+	audit    *AuditLog
+	policy   LoginPolicy
+}
+
+func NewLoginGuard(attempts repository.LoginAttemptRepository, audit *AuditLog, policy LoginPolicy) *LoginGuard {
+	return &LoginGuard{attempts: attempts, audit: audit, policy: policy}
+}
+
+// Check refuses a login while the email or the IP is over its limit. The
+// lockout lifts once the oldest counted failure leaves the window.
+func (g *LoginGuard) Check(ctx context.Context, email, ip string) error {
+	now := time.Now().UTC()
+	failures, err := g.attempts.CountFailures(ctx, email, ip, now.Add(-g.policy.Window))
+	if err != nil {
+		return err
+	}
+
+	locked := false
+	var retryAfter time.Duration
+	if failures.ByEmail >= g.policy.MaxEmailFailures {
+		locked = true
+		retryAfter = max(retryAfter, failures.OldestByEmail.Add(g.policy.Window).Sub(now))
+	}
+	if failures.ByIP >= g.policy.MaxIPFailures {
+		locked = true
+		retryAfter = max(retryAfter, failures.OldestByIP.Add(g.policy.Window).Sub(now))
+	}
+	if locked {
+		return &repository.TooManyRequestsError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+func (g *LoginGuard) Fail(ctx context.Context, email, ip string) error {
+	return g.attempts.RecordFailure(ctx, domain.LoginAttempt{
+		Email:     email,
+		IP:        ip,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+func (g *LoginGuard) Succeed(ctx context.Context, email string) error {
+	return g.attempts.Clear(ctx, email)
+}
+
+func (g *LoginGuard) Unlock(ctx context.Context, email string) error {
+	email = strings.TrimSpace(strings.ToLower(email))
+	if email == "" {
+		return repository.ErrInvalid
+	}
+	if err := g.attempts.Clear(ctx, email); err != nil {
+		return err
+	}
+	// The audit log is append-only, so it gets a hash rather than an address
+	// that could never be erased. Admins can hash an email to look it up.
+	g.audit.Record(ctx, domain.AuditUnlock, domain.AuditEntityLogin, hashToken(email), nil, nil)
+	return nil
+}
+
+func (g *LoginGuard) List(ctx context.Context, email string, limit int) ([]domain.LoginAttempt, error) {
+	if limit <= 0 || limit > maxLoginAttemptsPage {
+		limit = maxLoginAttemptsPage
+	}
+	return g.attempts.List(ctx, strings.TrimSpace(strings.ToLower(email)), limit)
+}
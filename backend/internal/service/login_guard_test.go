@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+	"islamdiplom/internal/repository/memory"
+)
+
+var testLoginPolicy = LoginPolicy{MaxEmailFailures: 3, MaxIPFailures: 5, Window: 15 * time.Minute}
+
+func recordFailures(t *testing.T, attempts *memory.LoginAttemptRepository, email, ip string, n int, at time.Time) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		err := attempts.RecordFailure(context.Background(), domain.LoginAttempt{Email: email, IP: ip, CreatedAt: at})
+		if err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+	}
+}
+
+func TestLoginGuardCheck(t *testing.T) {
+	now := time.Now().UTC()
+
+	type failure struct {
+		email string
+		ip    string
+		n     int
+		age   time.Duration
+	}
+	tests := []struct {
+		name     string
+		failures []failure
+		locked   bool
+	}{
+		{name: "no failures"},
+		{
+			name:     "below the email limit",
+			failures: []failure{{email: "user@example.com", ip: "10.0.0.1", n: 2, age: time.Minute}},
+		},
+		{
+			name:     "email limit reached",
+			failures: []failure{{email: "user@example.com", ip: "10.0.0.1", n: 3, age: time.Minute}},
+			locked:   true,
+		},
+		{
+			name:     "email limit reached from other IPs",
+			failures: []failure{{email: "user@example.com", ip: "10.0.0.9", n: 3, age: time.Minute}},
+			locked:   true,
+		},
+		{
+			name: "IP limit reached across emails",
+			failures: []failure{
+				{email: "a@example.com", ip: "10.0.0.1", n: 2, age: time.Minute},
+				{email: "b@example.com", ip: "10.0.0.1", n: 2, age: time.Minute},
+				{email: "c@example.com", ip: "10.0.0.1", n: 1, age: time.Minute},
+			},
+			locked: true,
+		},
+		{
+			name:     "other emails and IPs do not count",
+			failures: []failure{{email: "other@example.com", ip: "10.0.0.9", n: 10, age: time.Minute}},
+		},
+		{
+			name:     "failures outside the window expire",
+			failures: []failure{{email: "user@example.com", ip: "10.0.0.1", n: 5, age: 16 * time.Minute}},
+		},
+		{
+			name: "only failures inside the window count",
+			failures: []failure{
+				{email: "user@example.com", ip: "10.0.0.1", n: 2, age: 16 * time.Minute},
+				{email: "user@example.com", ip: "10.0.0.1", n: 2, age: time.Minute},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := memory.NewLoginAttemptRepository()
+			for _, f := range tt.failures {
+				recordFailures(t, attempts, f.email, f.ip, f.n, now.Add(-f.age))
+			}
+			guard := NewLoginGuard(attempts, nil, testLoginPolicy)
+
+			err := guard.Check(context.Background(), "user@example.com", "10.0.0.1")
+			if tt.locked != errors.Is(err, repository.ErrTooManyRequests) {
+				t.Fatalf("Check = %v, locked = %v", err, tt.locked)
+			}
+			if !tt.locked && err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoginGuardRetryAfterOldestFailure(t *testing.T) {
+	now := time.Now().UTC()
+	attempts := memory.NewLoginAttemptRepository()
+	recordFailures(t, attempts, "user@example.com", "10.0.0.1", 1, now.Add(-10*time.Minute))
+	recordFailures(t, attempts, "user@example.com", "10.0.0.1", 2, now.Add(-time.Minute))
+	guard := NewLoginGuard(attempts, nil, testLoginPolicy)
+
+	err := guard.Check(context.Background(), "user@example.com", "10.0.0.1")
+	var tooMany *repository.TooManyRequestsError
+	if !errors.As(err, &tooMany) {
+		t.Fatalf("Check = %v, want TooManyRequestsError", err)
+	}
+	// The oldest failure leaves the 15 minute window in about 5 minutes.
+	if tooMany.RetryAfter <= 4*time.Minute || tooMany.RetryAfter > 5*time.Minute {
+		t.Fatalf("RetryAfter = %v, want just under 5m", tooMany.RetryAfter)
+	}
+}
+
+func TestLoginGuardSucceedClearsEmailCount(t *testing.T) {
+	ctx := context.Background()
+	attempts := memory.NewLoginAttemptRepository()
+	guard := NewLoginGuard(attempts, nil, testLoginPolicy)
+
+	for i := 0; i < 2; i++ {
+		if err := guard.Fail(ctx, "user@example.com", "10.0.0.1"); err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+	}
+	if err := guard.Succeed(ctx, "user@example.com"); err != nil {
+		t.Fatalf("Succeed: %v", err)
+	}
+	// Without the clear, this third failure would lock the email.
+	if err := guard.Fail(ctx, "user@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("Fail: %v", err)
+	}
+	if err := guard.Check(ctx, "user@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("Check after Succeed: %v", err)
+	}
+}
+
+func TestLoginGuardSucceedKeepsIPCount(t *testing.T) {
+	ctx := context.Background()
+	attempts := memory.NewLoginAttemptRepository()
+	recordFailures(t, attempts, "user@example.com", "10.0.0.1", 5, time.Now().UTC())
+	guard := NewLoginGuard(attempts, nil, testLoginPolicy)
+
+	if err := guard.Succeed(ctx, "user@example.com"); err != nil {
+		t.Fatalf("Succeed: %v", err)
+	}
+	if err := guard.Check(ctx, "user@example.com", "10.0.0.1"); !errors.Is(err, repository.ErrTooManyRequests) {
+		t.Fatalf("Check = %v, want the IP to stay throttled", err)
+	}
+}
+
+func TestLoginGuardUnlock(t *testing.T) {
+	ctx := context.Background()
+	attempts := memory.NewLoginAttemptRepository()
+	recordFailures(t, attempts, "user@example.com", "10.0.0.1", 3, time.Now().UTC())
+	guard := NewLoginGuard(attempts, nil, testLoginPolicy)
+
+	if err := guard.Check(ctx, "user@example.com", "10.0.0.2"); !errors.Is(err, repository.ErrTooManyRequests) {
+		t.Fatalf("Check before Unlock = %v, want ErrTooManyRequests", err)
+	}
+	if err := guard.Unlock(ctx, "  User@Example.com "); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := guard.Check(ctx, "user@example.com", "10.0.0.2"); err != nil {
+		t.Fatalf("Check after Unlock: %v", err)
+	}
+	if err := guard.Unlock(ctx, " "); !errors.Is(err, repository.ErrInvalid) {
+		t.Fatalf("Unlock(blank) = %v, want ErrInvalid", err)
+	}
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+const (
+	totpPeriod        = 30
+	totpDigits        = 6
+	totpSkew          = 1
+	totpSecretSize    = 20
+	recoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type MFAEnrollment struct {
+	Secret string
+	URI    string
+}
+
+type MFAStatus struct {
+	Enabled  bool
+	Required bool
+}
+
+// MFAService manages TOTP (RFC 6238) second factors. Each accepted code
+// advances the stored time step, so a code cannot be replayed within its
+// validity window.
+type MFAService struct {
+	mfa           repository.MFARepository
+	users         repository.UserRepository
+	hasher        *PasswordHasher
+	issuer        string
+	requiredRoles map[string]bool
+	audit         *AuditLog
+}
+
+func NewMFAService(mfa repository.MFARepository, users repository.UserRepository, hasher *PasswordHasher, issuer string, requiredRoles []string, audit *AuditLog) *MFAService {
+	required := make(map[string]bool, len(requiredRoles))
+	for _, role := range requiredRoles {
+		role = strings.TrimSpace(role)
+		if role != "" {
+			required[role] = true
+		}
+	}
+	return &MFAService{
+		mfa:           mfa,
+		users:         users,
+		hasher:        hasher,
+		issuer:        issuer,
+		requiredRoles: required,
+		audit:         audit,
+	}
+}
+
+func (s *MFAService) Status(ctx context.Context, userID uuid.UUID) (MFAStatus, error) {
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return MFAStatus{}, err
+	}
+	enabled, err := s.Enabled(ctx, user.ID)
+	if err != nil {
+		return MFAStatus{}, err
+	}
+	return MFAStatus{Enabled: enabled, Required: s.requiredRoles[user.Role]}, nil
+}
+
+// Enroll starts enrollment with a fresh secret. It stays inactive until
+// Confirm proves the authenticator app produces matching codes.
+func (s *MFAService) Enroll(ctx context.Context, userID uuid.UUID) (MFAEnrollment, error) {
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+
+	raw := make([]byte, totpSecretSize)
+	if _, err := rand.Read(raw); err != nil {
+		return MFAEnrollment{}, err
+	}
+	secret := totpEncoding.EncodeToString(raw)
+
+	if err := s.mfa.SavePending(ctx, userID, secret); err != nil {
+		return MFAEnrollment{}, err
+	}
+
+	return MFAEnrollment{Secret: secret, URI: s.provisioningURI(user.Email, secret)}, nil
+}
+
+// Confirm activates a pending enrollment and returns recovery codes. They
+// are stored hashed, so this is the only time they can be shown.
+func (s *MFAService) Confirm(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	current, err := s.mfa.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if current.EnabledAt != nil {
+		return nil, repository.ErrConflict
+	}
+
+	step, ok := matchTOTP(current.Secret, code, time.Now())
+	if !ok {
+		return nil, repository.ErrInvalid
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(code)))
+	}
+
+	if err := s.mfa.Enable(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, domain.AuditEnable, domain.AuditEntityMFA, userID.String(), nil, nil)
+	return codes, nil
+}
+
+// Disable turns MFA off after checking the password, when the account has
+// one, and a current code. Accounts from an identity provider rely on the
+// code alone.
+func (s *MFAService) Disable(ctx context.Context, userID uuid.UUID, password, code string) error {
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash != "" {
+		ok, _, err := s.hasher.Verify(user.PasswordHash, password)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return repository.ErrUnauthorized
+		}
+	}
+
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	if err := s.mfa.Disable(ctx, userID); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditDisable, domain.AuditEntityMFA, userID.String(), nil, nil)
+	return nil
+}
+
+func (s *MFAService) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	current, err := s.mfa.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return current.EnabledAt != nil, nil
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code.
+func (s *MFAService) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	current, err := s.mfa.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return repository.ErrUnauthorized
+		}
+		return err
+	}
+	if current.EnabledAt == nil {
+		return repository.ErrUnauthorized
+	}
+
+	if step, ok := matchTOTP(current.Secret, code, time.Now()); ok {
+		if err := s.mfa.UseStep(ctx, userID, step); err != nil {
+			if errors.Is(err, repository.ErrConflict) {
+				return repository.ErrUnauthorized
+			}
+			return err
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return repository.ErrUnauthorized
+	}
+	if err := s.mfa.UseRecoveryCode(ctx, userID, hashToken(normalized)); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return repository.ErrUnauthorized
+		}
+		return err
+	}
+	return nil
+}
+
+// Enforce rejects users whose role requires MFA but who have not enrolled.
+func (s *MFAService) Enforce(ctx context.Context, user domain.User) error {
+	if !s.requiredRoles[user.Role] {
+		return nil
+	}
+	enabled, err := s.Enabled(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return repository.ErrForbidden
+	}
+	return nil
+}
+
+func (s *MFAService) provisioningURI(email, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", s.issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(s.issuer+":"+email) + "?" + query.Encode()
+}
+
+// matchTOTP checks the code against the current step and its neighbours to
+// tolerate clock drift, returning the step that matched.
+func matchTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for offset := -totpSkew; offset <= totpSkew; offset++ {
+		step := current + int64(offset)
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+func newRecoveryCode() (string, error) {
+	raw := make([]byte, 10)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	encoded := strings.ToLower(totpEncoding.EncodeToString(raw))
+	return encoded[:8] + "-" + encoded[8:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+// rfc6238Key is the SHA-1 seed from RFC 6238 Appendix B.
+var rfc6238Key = []byte("12345678901234567890")
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// Appendix B lists 8-digit codes; ours are their last 6 digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(rfc6238Key, tt.unix/totpPeriod); got != tt.want {
+			t.Fatalf("totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTOTPSkew(t *testing.T) {
+	secret := totpEncoding.EncodeToString(rfc6238Key)
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name   string
+		code   string
+		wantOK bool
+	}{
+		{name: "current step", code: totpCode(rfc6238Key, current), wantOK: true},
+		{name: "previous step", code: totpCode(rfc6238Key, current-1), wantOK: true},
+		{name: "next step", code: totpCode(rfc6238Key, current+1), wantOK: true},
+		{name: "two steps behind", code: totpCode(rfc6238Key, current-2)},
+		{name: "two steps ahead", code: totpCode(rfc6238Key, current+2)},
+		{name: "surrounding spaces", code: " " + totpCode(rfc6238Key, current) + " ", wantOK: true},
+		{name: "wrong length", code: "12345"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := matchTOTP(secret, tt.code, now)
+			if ok != tt.wantOK {
+				t.Fatalf("matchTOTP(%q) ok = %v, want %v", tt.code, ok, tt.wantOK)
+			}
+			if ok && (step < current-totpSkew || step > current+totpSkew) {
+				t.Fatalf("matched step %d outside %d±%d", step, current, totpSkew)
+			}
+		})
+	}
+}
+
+func TestMFAVerifyRejectsUsedStep(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	mfaRepo := newEnrolledMFA(userID)
+	mfa := NewMFAService(mfaRepo, &fakeUsers{byID: map[uuid.UUID]domain.User{}}, nil, "test", nil, nil)
+
+	current := time.Now().Unix() / totpPeriod
+	code := totpCode(rfc6238Key, current)
+	if err := mfa.Verify(ctx, userID, code); err != nil {
+		t.Fatalf("first Verify: %v", err)
+	}
+	if err := mfa.Verify(ctx, userID, code); !errors.Is(err, repository.ErrUnauthorized) {
+		t.Fatalf("replayed Verify = %v, want ErrUnauthorized", err)
+	}
+	// An older code still inside the skew is spent too.
+	if err := mfa.Verify(ctx, userID, totpCode(rfc6238Key, current-1)); !errors.Is(err, repository.ErrUnauthorized) {
+		t.Fatalf("older Verify = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestMFADisable(t *testing.T) {
+	hasher := mustHasher(t, HashArgon2id, testArgon2)
+	passwordHash := mustHash(t, hasher, "correct horse")
+
+	tests := []struct {
+		name         string
+		passwordHash string
+		password     string
+		wantErr      error
+	}{
+		{name: "password and code", passwordHash: passwordHash, password: "correct horse"},
+		{name: "wrong password", passwordHash: passwordHash, password: "wrong", wantErr: repository.ErrUnauthorized},
+		{name: "missing password", passwordHash: passwordHash, wantErr: repository.ErrUnauthorized},
+		{name: "passwordless account relies on the code", passwordHash: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			users := &fakeUsers{byID: map[uuid.UUID]domain.User{userID: {ID: userID, PasswordHash: tt.passwordHash}}}
+			mfaRepo := newEnrolledMFA(userID)
+			mfa := NewMFAService(mfaRepo, users, hasher, "test", nil, nil)
+
+			code := totpCode(rfc6238Key, time.Now().Unix()/totpPeriod)
+			err := mfa.Disable(context.Background(), userID, tt.password, code)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Disable = %v, want %v", err, tt.wantErr)
+				}
+				if mfaRepo.state.EnabledAt == nil {
+					t.Fatal("MFA was disabled after a rejected attempt")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Disable: %v", err)
+			}
+			if mfaRepo.state.EnabledAt != nil {
+				t.Fatal("MFA is still enabled")
+			}
+		})
+	}
+
+	t.Run("passwordless account without a code", func(t *testing.T) {
+		userID := uuid.New()
+		users := &fakeUsers{byID: map[uuid.UUID]domain.User{userID: {ID: userID}}}
+		mfa := NewMFAService(newEnrolledMFA(userID), users, hasher, "test", nil, nil)
+
+		if err := mfa.Disable(context.Background(), userID, "", "000000"); !errors.Is(err, repository.ErrUnauthorized) {
+			t.Fatalf("Disable = %v, want ErrUnauthorized", err)
+		}
+	})
+}
+
+// enrolledMFA holds one enabled TOTP factor seeded with rfc6238Key and
+// advances the used step the way the Postgres repository does.
+type enrolledMFA struct {
+	repository.MFARepository
+	state domain.MFA
+}
+
+func newEnrolledMFA(userID uuid.UUID) *enrolledMFA {
+	enabledAt := time.Now().UTC()
+	return &enrolledMFA{state: domain.MFA{
+		UserID:    userID,
+		Secret:    totpEncoding.EncodeToString(rfc6238Key),
+		EnabledAt: &enabledAt,
+	}}
+}
+
+func (r *enrolledMFA) Get(_ context.Context, userID uuid.UUID) (domain.MFA, error) {
+	if userID != r.state.UserID || r.state.Secret == "" {
+		return domain.MFA{}, repository.ErrNotFound
+	}
+	return r.state, nil
+}
+
+func (r *enrolledMFA) UseStep(_ context.Context, _ uuid.UUID, step int64) error {
+	if r.state.LastStep >= step {
+		return repository.ErrConflict
+	}
+	r.state.LastStep = step
+	return nil
+}
+
+func (r *enrolledMFA) UseRecoveryCode(context.Context, uuid.UUID, string) error {
+	return repository.ErrNotFound
+}
+
+func (r *enrolledMFA) Disable(context.Context, uuid.UUID) error {
+	r.state.EnabledAt = nil
+	return nil
+}
@@ -0,0 +1,465 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+const (
+	oidcStateTTL        = 10 * time.Minute
+	oidcKeysMinInterval = time.Minute
+	oidcMaxResponseSize = 1 << 20
+)
+
+var errOIDCProvider = errors.New("identity provider error")
+
+// OIDCProviderConfig describes one identity provider. Endpoints are taken
+// from the issuer's discovery document unless set explicitly, which is
+// needed for providers without discovery and handy for local fake servers.
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	AuthURL      string
+	TokenURL     string
+	JWKSURL      string
+}
+
+type oidcProvider struct {
+	config OIDCProviderConfig
+
+	mu          sync.Mutex
+	discovered  bool
+	authURL     string
+	tokenURL    string
+	jwksURL     string
+	keys        map[string]any
+	keysFetched time.Time
+}
+
+// OIDCService signs users in with the OpenID Connect authorization code
+// flow and PKCE. The SPA is the redirect target: it starts the flow, gets
+// the code back from the provider and hands code and state to Callback, so
+// the verifier never leaves the server and tokens never appear in URLs.
+type OIDCService struct {
+	auth      *AuthService
+	users     repository.UserRepository
+	oidc      repository.OIDCRepository
+	client    *http.Client
+	providers map[string]*oidcProvider
+}
+
+func NewOIDCService(auth *AuthService, users repository.UserRepository, oidc repository.OIDCRepository, client *http.Client, providers []OIDCProviderConfig) *OIDCService {
+	configured := make(map[string]*oidcProvider, len(providers))
+	for _, provider := range providers {
+		configured[provider.Name] = &oidcProvider{config: provider}
+	}
+	return &OIDCService{
+		auth:      auth,
+		users:     users,
+		oidc:      oidc,
+		client:    client,
+		providers: configured,
+	}
+}
+
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start returns the provider URL the browser should be sent to.
+func (s *OIDCService) Start(ctx context.Context, providerName string) (string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", repository.ErrNotFound
+	}
+	if err := s.discover(ctx, provider); err != nil {
+		return "", err
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.oidc.CreateState(ctx, domain.OIDCLoginState{
+		StateHash:    hashToken(state),
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(oidcStateTTL).UTC(),
+	}); err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.config.ClientID)
+	query.Set("redirect_uri", provider.config.RedirectURL)
+	query.Set("scope", strings.Join(provider.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(provider.authURL, "?") {
+		separator = "&"
+	}
+	return provider.authURL + separator + query.Encode(), nil
+}
+
+// Callback finishes the flow. A known external identity signs in its user;
+// otherwise the account is matched or created by the provider-verified email.
+func (s *OIDCService) Callback(ctx context.Context, providerName, code, state string, client ClientInfo) (domain.User, TokenPair, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return domain.User{}, TokenPair{}, repository.ErrNotFound
+	}
+	if code == "" || state == "" {
+		return domain.User{}, TokenPair{}, repository.ErrInvalid
+	}
+
+	login, err := s.oidc.ConsumeState(ctx, hashToken(state), time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return domain.User{}, TokenPair{}, repository.ErrUnauthorized
+		}
+		return domain.User{}, TokenPair{}, err
+	}
+	if login.Provider != providerName {
+		return domain.User{}, TokenPair{}, repository.ErrUnauthorized
+	}
+	if err := s.discover(ctx, provider); err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+
+	rawIDToken, err := s.exchange(ctx, provider, code, login.CodeVerifier)
+	if err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+	claims, err := s.verifyIDToken(ctx, provider, rawIDToken, login.Nonce)
+	if err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+
+	user, err := s.resolveUser(ctx, providerName, claims)
+	if err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+
+	tokens, err := s.auth.completeLogin(ctx, user, client)
+	if err != nil {
+		return domain.User{}, TokenPair{}, err
+	}
+	return user, tokens, nil
+}
+
+type idTokenClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+func (s *OIDCService) resolveUser(ctx context.Context, providerName string, claims idTokenClaims) (domain.User, error) {
+	identity, err := s.oidc.GetIdentity(ctx, providerName, claims.Subject)
+	if err == nil {
+		user, err := s.users.Get(ctx, identity.UserID)
+		if err != nil {
+			return domain.User{}, err
+		}
+		if err := s.oidc.LinkIdentity(ctx, domain.ExternalIdentity{
+			Provider: providerName,
+			Subject:  claims.Subject,
+			UserID:   user.ID,
+			Email:    claims.Email,
+		}); err != nil {
+			return domain.User{}, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return domain.User{}, err
+	}
+
+	// Linking by email is only safe when the provider vouches for it.
+	if !claims.EmailVerified {
+		return domain.User{}, repository.ErrForbidden
+	}
+	email, err := normalizeEmail(claims.Email)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	user, err := s.users.GetByEmail(ctx, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		user, err = s.users.Create(ctx, domain.User{Email: email})
+	} else if err == nil && user.EmailVerifiedAt == nil {
+		// Whoever registered this address never proved they own it and may be
+		// waiting for the owner to sign in here. Their password, second factor
+		// and sessions must not survive the owner taking the account over.
+		if err = s.auth.dropCredentials(ctx, user.ID); err == nil {
+			user.PasswordHash = ""
+		}
+	}
+	if err != nil {
+		return domain.User{}, err
+	}
+	if user.EmailVerifiedAt == nil {
+		now := time.Now().UTC()
+		if err := s.users.MarkEmailVerified(ctx, user.ID, now); err != nil {
+			return domain.User{}, err
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := s.oidc.LinkIdentity(ctx, domain.ExternalIdentity{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		UserID:   user.ID,
+		Email:    email,
+	}); err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+func (s *OIDCService) discover(ctx context.Context, provider *oidcProvider) error {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if provider.discovered {
+		return nil
+	}
+
+	config := provider.config
+	provider.authURL, provider.tokenURL, provider.jwksURL = config.AuthURL, config.TokenURL, config.JWKSURL
+	if provider.authURL == "" || provider.tokenURL == "" || provider.jwksURL == "" {
+		var document struct {
+			Issuer                string `json:"issuer"`
+			AuthorizationEndpoint string `json:"authorization_endpoint"`
+			TokenEndpoint         string `json:"token_endpoint"`
+			JWKSURI               string `json:"jwks_uri"`
+		}
+		endpoint := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := s.getJSON(ctx, endpoint, &document); err != nil {
+			return err
+		}
+		if document.Issuer != config.Issuer {
+			return fmt.Errorf("%w: discovery issuer %q does not match %q", errOIDCProvider, document.Issuer, config.Issuer)
+		}
+		if provider.authURL == "" {
+			provider.authURL = document.AuthorizationEndpoint
+		}
+		if provider.tokenURL == "" {
+			provider.tokenURL = document.TokenEndpoint
+		}
+		if provider.jwksURL == "" {
+			provider.jwksURL = document.JWKSURI
+		}
+	}
+	if provider.authURL == "" || provider.tokenURL == "" || provider.jwksURL == "" {
+		return fmt.Errorf("%w: incomplete endpoints for %s", errOIDCProvider, config.Name)
+	}
+
+	provider.discovered = true
+	return nil
+}
+
+func (s *OIDCService) exchange(ctx context.Context, provider *oidcProvider, code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", provider.config.RedirectURL)
+	form.Set("client_id", provider.config.ClientID)
+	form.Set("code_verifier", verifier)
+	if provider.config.ClientSecret != "" {
+		form.Set("client_secret", provider.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+		// invalid_grant and friends: the code was wrong, reused or expired.
+		return "", repository.ErrUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: token endpoint returned %d", errOIDCProvider, resp.StatusCode)
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.IDToken == "" {
+		return "", fmt.Errorf("%w: no id_token in token response", errOIDCProvider)
+	}
+	return token.IDToken, nil
+}
+
+func (s *OIDCService) verifyIDToken(ctx context.Context, provider *oidcProvider, raw, nonce string) (idTokenClaims, error) {
+	claims := jwt.MapClaims{}
+	parsed, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return s.providerKey(ctx, provider, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(provider.config.Issuer),
+		jwt.WithAudience(provider.config.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !parsed.Valid {
+		return idTokenClaims{}, repository.ErrUnauthorized
+	}
+
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return idTokenClaims{}, repository.ErrUnauthorized
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return idTokenClaims{}, repository.ErrUnauthorized
+	}
+
+	result := idTokenClaims{Subject: subject}
+	result.Email, _ = claims["email"].(string)
+	// Some providers send email_verified as a string.
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+	return result, nil
+}
+
+// providerKey looks a signing key up by kid, refetching the provider JWKS
+// when the kid is unknown because the provider may have rotated its keys.
+func (s *OIDCService) providerKey(ctx context.Context, provider *oidcProvider, kid string) (any, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if key, ok := provider.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(provider.keysFetched) < oidcKeysMinInterval {
+		return nil, repository.ErrUnauthorized
+	}
+
+	var set struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			Use     string `json:"use"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+			Curve   string `json:"crv"`
+			X       string `json:"x"`
+			Y       string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := s.getJSON(ctx, provider.jwksURL, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		switch jwk.KeyType {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[jwk.KeyID] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			if jwk.Curve != "P-256" {
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.KeyID] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	provider.keys = keys
+	provider.keysFetched = time.Now()
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, repository.ErrUnauthorized
+	}
+	return key, nil
+}
+
+func (s *OIDCService) getJSON(ctx context.Context, endpoint string, target any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned %d", errOIDCProvider, endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(target)
+}
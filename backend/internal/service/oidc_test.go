@@ -0,0 +1,498 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+	"islamdiplom/internal/repository/memory"
+)
+
+const (
+	testClientID = "test-client"
+	testKeyID    = "test-key"
+)
+
+// fakeProvider is a minimal OpenID provider: discovery, JWKS and a token
+// endpoint that checks the PKCE verifier against the challenge it was given
+// for the code and returns a signed id_token.
+type fakeProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]fakeGrant
+}
+
+type fakeGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+	signer    *rsa.PrivateKey
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	p := &fakeProvider{key: mustRSAKey(t), codes: map[string]fakeGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testKeyID,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		p.mu.Lock()
+		grant, ok := p.codes[r.PostForm.Get("code")]
+		delete(p.codes, r.PostForm.Get("code"))
+		p.mu.Unlock()
+
+		if !ok || r.PostForm.Get("client_id") != testClientID || pkceChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			writeTestJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+		token.Header["kid"] = testKeyID
+		signed, err := token.SignedString(grant.signer)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeTestJSON(w, map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// authorize plays the provider's login page: it reads the authorization URL
+// from Start, issues a code bound to its PKCE challenge and returns the code
+// and state the SPA would pass to Callback. edit may change the claims or
+// the grant before the code is issued.
+func (p *fakeProvider) authorize(t *testing.T, authURL string, edit func(*fakeGrant)) (string, string) {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse auth url: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q, want S256", query.Get("code_challenge_method"))
+	}
+
+	now := time.Now()
+	grant := fakeGrant{
+		challenge: query.Get("code_challenge"),
+		signer:    p.key,
+		claims: jwt.MapClaims{
+			"iss":            p.server.URL,
+			"aud":            testClientID,
+			"sub":            "subject-1",
+			"email":          "user@example.com",
+			"email_verified": true,
+			"nonce":          query.Get("nonce"),
+			"iat":            now.Unix(),
+			"exp":            now.Add(5 * time.Minute).Unix(),
+		},
+	}
+	if edit != nil {
+		edit(&grant)
+	}
+
+	code := uuid.NewString()
+	p.mu.Lock()
+	p.codes[code] = grant
+	p.mu.Unlock()
+	return code, query.Get("state")
+}
+
+type oidcFixture struct {
+	service  *OIDCService
+	provider *fakeProvider
+	users    *fakeUsers
+	store    *fakeOIDCStore
+	mfa      *fakeMFA
+	sessions *fakeSessions
+}
+
+func newOIDCFixture(t *testing.T) *oidcFixture {
+	t.Helper()
+	provider := newFakeProvider(t)
+	users := &fakeUsers{byID: map[uuid.UUID]domain.User{}}
+	store := &fakeOIDCStore{states: map[string]domain.OIDCLoginState{}, identities: map[string]domain.ExternalIdentity{}}
+
+	mfaRepo := &fakeMFA{}
+	sessions := &fakeSessions{}
+	mfa := NewMFAService(mfaRepo, users, nil, "test", nil, nil)
+	guard := NewLoginGuard(memory.NewLoginAttemptRepository(), nil, LoginPolicy{MaxEmailFailures: 5, MaxIPFailures: 20, Window: time.Minute})
+	auth := NewAuthService(users, fakeRefreshTokens{}, sessions, nil, guard, nil, nil, mfa, NewHMACTokenKeys("test-secret"), nil, time.Minute, time.Hour)
+
+	service := NewOIDCService(auth, users, store, provider.server.Client(), []OIDCProviderConfig{{
+		Name:        "fake",
+		Issuer:      provider.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost/auth/callback",
+		Scopes:      []string{"openid", "email"},
+	}})
+	return &oidcFixture{service: service, provider: provider, users: users, store: store, mfa: mfaRepo, sessions: sessions}
+}
+
+func (f *oidcFixture) login(t *testing.T, edit func(*fakeGrant)) (domain.User, TokenPair, error) {
+	t.Helper()
+	authURL, err := f.service.Start(context.Background(), "fake")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	code, state := f.provider.authorize(t, authURL, edit)
+	return f.service.Callback(context.Background(), "fake", code, state, ClientInfo{IP: "127.0.0.1"})
+}
+
+func TestOIDCCallback(t *testing.T) {
+	rogue := mustRSAKey(t)
+
+	tests := []struct {
+		name string
+		edit func(*fakeGrant)
+	}{
+		{
+			name: "nonce mismatch",
+			edit: func(g *fakeGrant) { g.claims["nonce"] = "replayed" },
+		},
+		{
+			name: "PKCE verifier does not match the challenge",
+			edit: func(g *fakeGrant) { g.challenge = pkceChallenge("someone else's verifier") },
+		},
+		{
+			name: "bad signature",
+			edit: func(g *fakeGrant) { g.signer = rogue },
+		},
+		{
+			name: "wrong audience",
+			edit: func(g *fakeGrant) { g.claims["aud"] = "another-client" },
+		},
+		{
+			name: "wrong issuer",
+			edit: func(g *fakeGrant) { g.claims["iss"] = "https://evil.example.com" },
+		},
+		{
+			name: "expired token",
+			edit: func(g *fakeGrant) { g.claims["exp"] = time.Now().Add(-time.Minute).Unix() },
+		},
+		{
+			name: "missing subject",
+			edit: func(g *fakeGrant) { delete(g.claims, "sub") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOIDCFixture(t)
+			_, _, err := f.login(t, tt.edit)
+			if !errors.Is(err, repository.ErrUnauthorized) {
+				t.Fatalf("err = %v, want ErrUnauthorized", err)
+			}
+			if len(f.users.byID) != 0 || len(f.store.identities) != 0 {
+				t.Fatalf("rejected login created %d users and %d identities", len(f.users.byID), len(f.store.identities))
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackCreatesUser(t *testing.T) {
+	f := newOIDCFixture(t)
+
+	user, tokens, err := f.login(t, nil)
+	if err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	if user.Email != "user@example.com" || user.EmailVerifiedAt == nil {
+		t.Fatalf("user = %+v, want a verified user@example.com", user)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("tokens = %+v, want an access and a refresh token", tokens)
+	}
+	if identity, ok := f.store.identities["fake/subject-1"]; !ok || identity.UserID != user.ID {
+		t.Fatalf("identity = %+v, want a link to %s", identity, user.ID)
+	}
+
+	again, _, err := f.login(t, func(g *fakeGrant) { g.claims["email"] = "renamed@example.com" })
+	if err != nil {
+		t.Fatalf("second callback: %v", err)
+	}
+	if again.ID != user.ID {
+		t.Fatalf("known subject signed in as %s, want %s", again.ID, user.ID)
+	}
+}
+
+func TestOIDCCallbackState(t *testing.T) {
+	f := newOIDCFixture(t)
+	ctx := context.Background()
+
+	authURL, err := f.service.Start(ctx, "fake")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	code, state := f.provider.authorize(t, authURL, nil)
+
+	if _, _, err := f.service.Callback(ctx, "fake", code, "forged-state", ClientInfo{}); !errors.Is(err, repository.ErrUnauthorized) {
+		t.Fatalf("unknown state: err = %v, want ErrUnauthorized", err)
+	}
+	if _, _, err := f.service.Callback(ctx, "fake", code, state, ClientInfo{}); err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	if _, _, err := f.service.Callback(ctx, "fake", code, state, ClientInfo{}); !errors.Is(err, repository.ErrUnauthorized) {
+		t.Fatalf("reused state: err = %v, want ErrUnauthorized", err)
+	}
+
+	f.store.states[hashToken("stale")] = domain.OIDCLoginState{Provider: "fake", ExpiresAt: time.Now().Add(-time.Second)}
+	if _, _, err := f.service.Callback(ctx, "fake", "code", "stale", ClientInfo{}); !errors.Is(err, repository.ErrUnauthorized) {
+		t.Fatalf("expired state: err = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestOIDCCallbackLinksVerifiedEmailOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		verified any
+		wantErr  error
+	}{
+		{name: "unverified email", verified: false, wantErr: repository.ErrForbidden},
+		{name: "missing email_verified", verified: nil, wantErr: repository.ErrForbidden},
+		{name: "verified email", verified: true},
+		{name: "verified email as a string", verified: "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOIDCFixture(t)
+			verifiedAt := time.Now().Add(-time.Hour)
+			existing, _ := f.users.Create(context.Background(), domain.User{Email: "user@example.com", PasswordHash: "hash", EmailVerifiedAt: &verifiedAt})
+
+			user, _, err := f.login(t, func(g *fakeGrant) {
+				if tt.verified == nil {
+					delete(g.claims, "email_verified")
+				} else {
+					g.claims["email_verified"] = tt.verified
+				}
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if len(f.store.identities) != 0 {
+					t.Fatalf("identity linked for an unverified email")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("callback: %v", err)
+			}
+			if user.ID != existing.ID {
+				t.Fatalf("signed in as %s, want the existing user %s", user.ID, existing.ID)
+			}
+			if f.store.identities["fake/subject-1"].UserID != existing.ID {
+				t.Fatalf("identity not linked to the existing user")
+			}
+			if f.users.byID[existing.ID].PasswordHash != "hash" || len(f.sessions.revokedFor) != 0 {
+				t.Fatalf("linking a verified account touched its credentials")
+			}
+		})
+	}
+}
+
+// An attacker registers the victim's address with a password and waits for
+// the victim to sign in through the provider. The link must not leave the
+// attacker a way back in.
+func TestOIDCCallbackTakesOverUnverifiedAccount(t *testing.T) {
+	f := newOIDCFixture(t)
+	squatter, _ := f.users.Create(context.Background(), domain.User{Email: "user@example.com", PasswordHash: "attacker's hash"})
+
+	user, _, err := f.login(t, nil)
+	if err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	if user.ID != squatter.ID || user.EmailVerifiedAt == nil {
+		t.Fatalf("user = %+v, want the existing account, now verified", user)
+	}
+	if hash := f.users.byID[squatter.ID].PasswordHash; hash != "" {
+		t.Fatalf("password hash = %q, want it cleared", hash)
+	}
+	if len(f.mfa.disabledFor) != 1 || f.mfa.disabledFor[0] != squatter.ID {
+		t.Fatalf("second factor not removed: %v", f.mfa.disabledFor)
+	}
+	if len(f.sessions.revokedFor) != 1 || f.sessions.revokedFor[0] != squatter.ID {
+		t.Fatalf("sessions not revoked: %v", f.sessions.revokedFor)
+	}
+}
+
+func mustRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func writeTestJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// The fakes below implement only what the OIDC flow touches; anything else
+// panics through the embedded nil interface.
+
+type fakeUsers struct {
+	repository.UserRepository
+	byID map[uuid.UUID]domain.User
+}
+
+func (r *fakeUsers) Create(_ context.Context, user domain.User) (domain.User, error) {
+	user.ID = uuid.New()
+	r.byID[user.ID] = user
+	return user, nil
+}
+
+func (r *fakeUsers) Get(_ context.Context, id uuid.UUID) (domain.User, error) {
+	user, ok := r.byID[id]
+	if !ok {
+		return domain.User{}, repository.ErrNotFound
+	}
+	return user, nil
+}
+
+func (r *fakeUsers) GetByEmail(_ context.Context, email string) (domain.User, error) {
+	for _, user := range r.byID {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return domain.User{}, repository.ErrNotFound
+}
+
+func (r *fakeUsers) UpdatePassword(_ context.Context, id uuid.UUID, passwordHash string) error {
+	user := r.byID[id]
+	user.PasswordHash = passwordHash
+	r.byID[id] = user
+	return nil
+}
+
+func (r *fakeUsers) MarkEmailVerified(_ context.Context, id uuid.UUID, at time.Time) error {
+	user := r.byID[id]
+	user.EmailVerifiedAt = &at
+	r.byID[id] = user
+	return nil
+}
+
+type fakeOIDCStore struct {
+	states     map[string]domain.OIDCLoginState
+	identities map[string]domain.ExternalIdentity
+}
+
+func (r *fakeOIDCStore) CreateState(_ context.Context, state domain.OIDCLoginState) error {
+	r.states[state.StateHash] = state
+	return nil
+}
+
+func (r *fakeOIDCStore) ConsumeState(_ context.Context, stateHash string, now time.Time) (domain.OIDCLoginState, error) {
+	state, ok := r.states[stateHash]
+	delete(r.states, stateHash)
+	if !ok || !state.ExpiresAt.After(now) {
+		return domain.OIDCLoginState{}, repository.ErrNotFound
+	}
+	return state, nil
+}
+
+func (r *fakeOIDCStore) GetIdentity(_ context.Context, provider, subject string) (domain.ExternalIdentity, error) {
+	identity, ok := r.identities[provider+"/"+subject]
+	if !ok {
+		return domain.ExternalIdentity{}, repository.ErrNotFound
+	}
+	return identity, nil
+}
+
+func (r *fakeOIDCStore) LinkIdentity(_ context.Context, identity domain.ExternalIdentity) error {
+	r.identities[identity.Provider+"/"+identity.Subject] = identity
+	return nil
+}
+
+type fakeMFA struct {
+	repository.MFARepository
+	disabledFor []uuid.UUID
+}
+
+func (*fakeMFA) Get(context.Context, uuid.UUID) (domain.MFA, error) {
+	return domain.MFA{}, repository.ErrNotFound
+}
+
+func (r *fakeMFA) Disable(_ context.Context, userID uuid.UUID) error {
+	r.disabledFor = append(r.disabledFor, userID)
+	return nil
+}
+
+type fakeSessions struct {
+	repository.SessionRepository
+	revokedFor []uuid.UUID
+}
+
+func (*fakeSessions) Create(_ context.Context, session domain.Session) (domain.Session, error) {
+	return session, nil
+}
+
+func (r *fakeSessions) RevokeAllForUser(_ context.Context, userID uuid.UUID, _ time.Time) error {
+	r.revokedFor = append(r.revokedFor, userID)
+	return nil
+}
+
+type fakeRefreshTokens struct {
+	repository.RefreshTokenRepository
+}
+
+func (fakeRefreshTokens) Create(_ context.Context, token domain.RefreshToken) (domain.RefreshToken, error) {
+	token.ID = uuid.New()
+	return token, nil
+}
+
+func (fakeRefreshTokens) RevokeAllForUser(context.Context, uuid.UUID) error {
+	return nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"islamdiplom/internal/repository"
+)
+
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+
+	// bcryptMaxPassword is the longest input bcrypt accepts. The policy
+	// counts runes, and a Cyrillic password can pass it at twice this size.
+	bcryptMaxPassword = 72
+)
+
+var errUnknownHash = errors.New("unknown password hash format")
+
+type Argon2Params struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+// PasswordHasher hashes new passwords with the configured algorithm and
+// verifies hashes produced by any supported one, so stored bcrypt hashes keep
+// working after switching to argon2id.
+type PasswordHasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     Argon2Params
+
+	dummyOnce sync.Once
+	dummy     string
+}
+
+func NewPasswordHasher(algorithm string, bcryptCost int, argon2 Argon2Params) (*PasswordHasher, error) {
+	if algorithm != HashBcrypt && algorithm != HashArgon2id {
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", algorithm)
+	}
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost %d out of range", bcryptCost)
+	}
+	if argon2.Memory == 0 || argon2.Time == 0 || argon2.Threads == 0 {
+		return nil, errors.New("argon2 parameters must be positive")
+	}
+	return &PasswordHasher{algorithm: algorithm, bcryptCost: bcryptCost, argon2: argon2}, nil
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.algorithm == HashBcrypt {
+		if len(password) > bcryptMaxPassword {
+			return "", repository.ErrInvalid
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.argon2.Time, h.argon2.Memory, h.argon2.Threads, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.argon2.Memory,
+		h.argon2.Time,
+		h.argon2.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether password matches hash and whether the hash should be
+// replaced because it was made with another algorithm or other parameters.
+func (h *PasswordHasher) Verify(hash, password string) (bool, bool, error) {
+	if hash == "" {
+		// Accounts created through an identity provider have no password.
+		return false, false, nil
+	}
+	if strings.HasPrefix(hash, "$argon2id$") {
+		return h.verifyArgon2(hash, password)
+	}
+	if strings.HasPrefix(hash, "$2") {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return false, false, err
+		}
+		return true, h.algorithm != HashBcrypt || cost != h.bcryptCost, nil
+	}
+	return false, false, errUnknownHash
+}
+
+// VerifyDummy spends as long as Verify on a real hash made with the current
+// settings, so a login for an unknown email or a passwordless account cannot
+// be told apart from a wrong password by its latency.
+func (h *PasswordHasher) VerifyDummy(password string) {
+	h.dummyOnce.Do(func() {
+		h.dummy, _ = h.Hash("dummy password for timing")
+	})
+	_, _, _ = h.Verify(h.dummy, password)
+}
+
+func (h *PasswordHasher) verifyArgon2(hash, password string) (bool, bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, false, errUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, errUnknownHash
+	}
+	var params Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return false, false, errUnknownHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, errUnknownHash
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, false, errUnknownHash
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(expected)))
+	if subtle.ConstantTimeCompare(key, expected) != 1 {
+		return false, false, nil
+	}
+	return true, h.algorithm != HashArgon2id || params != h.argon2, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"islamdiplom/internal/repository"
+)
+
+var testArgon2 = Argon2Params{Memory: 64, Time: 1, Threads: 1}
+
+func mustHasher(t *testing.T, algorithm string, argon Argon2Params) *PasswordHasher {
+	t.Helper()
+	hasher, err := NewPasswordHasher(algorithm, bcrypt.MinCost, argon)
+	if err != nil {
+		t.Fatalf("new hasher: %v", err)
+	}
+	return hasher
+}
+
+func mustHash(t *testing.T, hasher *PasswordHasher, password string) string {
+	t.Helper()
+	hash, err := hasher.Hash(password)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	return hash
+}
+
+func TestPasswordHasherFormat(t *testing.T) {
+	hash := mustHash(t, mustHasher(t, HashArgon2id, testArgon2), "correct horse")
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("hash = %q, want a PHC argon2id string with the configured parameters", hash)
+	}
+	if parts := strings.Split(hash, "$"); len(parts) != 6 {
+		t.Fatalf("hash has %d parts, want 6", len(parts))
+	}
+}
+
+func TestPasswordHasherVerify(t *testing.T) {
+	argonHasher := mustHasher(t, HashArgon2id, testArgon2)
+	bcryptHasher := mustHasher(t, HashBcrypt, testArgon2)
+	argonHash := mustHash(t, argonHasher, "correct horse")
+	bcryptHash := mustHash(t, bcryptHasher, "correct horse")
+	strongerBcrypt, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost+1)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+	otherParams := mustHash(t, mustHasher(t, HashArgon2id, Argon2Params{Memory: 128, Time: 1, Threads: 1}), "correct horse")
+
+	tests := []struct {
+		name       string
+		hasher     *PasswordHasher
+		hash       string
+		password   string
+		wantOK     bool
+		wantRehash bool
+		wantErr    bool
+	}{
+		{name: "argon2id match", hasher: argonHasher, hash: argonHash, password: "correct horse", wantOK: true},
+		{name: "argon2id mismatch", hasher: argonHasher, hash: argonHash, password: "battery staple"},
+		{name: "argon2id with old parameters is rehashed", hasher: argonHasher, hash: otherParams, password: "correct horse", wantOK: true, wantRehash: true},
+		{name: "bcrypt match under bcrypt", hasher: bcryptHasher, hash: bcryptHash, password: "correct horse", wantOK: true},
+		{name: "bcrypt is still accepted under argon2id", hasher: argonHasher, hash: bcryptHash, password: "correct horse", wantOK: true, wantRehash: true},
+		{name: "bcrypt mismatch", hasher: argonHasher, hash: bcryptHash, password: "battery staple"},
+		{name: "bcrypt with another cost is rehashed", hasher: bcryptHasher, hash: string(strongerBcrypt), password: "correct horse", wantOK: true, wantRehash: true},
+		{name: "argon2id is rehashed under bcrypt", hasher: bcryptHasher, hash: argonHash, password: "correct horse", wantOK: true, wantRehash: true},
+		{name: "no password set", hasher: argonHasher, hash: "", password: "correct horse"},
+		{name: "unknown scheme", hasher: argonHasher, hash: "$pbkdf2$abc", password: "correct horse", wantErr: true},
+		{name: "missing PHC part", hasher: argonHasher, hash: "$argon2id$v=19$m=64,t=1,p=1$c2FsdA", password: "x", wantErr: true},
+		{name: "wrong argon2 version", hasher: argonHasher, hash: "$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5", password: "x", wantErr: true},
+		{name: "malformed parameters", hasher: argonHasher, hash: "$argon2id$v=19$m=64;t=1$c2FsdA$a2V5", password: "x", wantErr: true},
+		{name: "salt is not base64", hasher: argonHasher, hash: "$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5", password: "x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash, err := tt.hasher.Verify(tt.hash, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK || rehash != tt.wantRehash {
+				t.Fatalf("Verify = (%v, %v), want (%v, %v)", ok, rehash, tt.wantOK, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestPasswordHasherBcryptLimit(t *testing.T) {
+	hasher := mustHasher(t, HashBcrypt, testArgon2)
+
+	// 36 Cyrillic letters are 72 bytes, the most bcrypt takes.
+	if _, err := hasher.Hash(strings.Repeat("ж", 36)); err != nil {
+		t.Fatalf("72 bytes: %v", err)
+	}
+	if _, err := hasher.Hash(strings.Repeat("ж", 37)); !errors.Is(err, repository.ErrInvalid) {
+		t.Fatalf("74 bytes: err = %v, want ErrInvalid", err)
+	}
+	if _, err := mustHasher(t, HashArgon2id, testArgon2).Hash(strings.Repeat("ж", 128)); err != nil {
+		t.Fatalf("argon2id has no byte limit: %v", err)
+	}
+}
+
+func TestNewPasswordHasherRejectsBadSettings(t *testing.T) {
+	if _, err := NewPasswordHasher("scrypt", bcrypt.MinCost, testArgon2); err == nil {
+		t.Fatal("unknown algorithm accepted")
+	}
+	if _, err := NewPasswordHasher(HashBcrypt, bcrypt.MaxCost+1, testArgon2); err == nil {
+		t.Fatal("bcrypt cost out of range accepted")
+	}
+	if _, err := NewPasswordHasher(HashArgon2id, bcrypt.MinCost, Argon2Params{Memory: 64, Time: 0, Threads: 1}); err == nil {
+		t.Fatal("zero argon2 time accepted")
+	}
+}
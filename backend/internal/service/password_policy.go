@@ -0,0 +1,66 @@
+package service
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"islamdiplom/internal/repository"
+)
+
+type PasswordPolicy struct {
+	minLength int
+	maxLength int
+	breached  map[string]struct{}
+}
+
+func NewPasswordPolicy(minLength, maxLength int, breached map[string]struct{}) *PasswordPolicy {
+	return &PasswordPolicy{minLength: minLength, maxLength: maxLength, breached: breached}
+}
+
+func (p *PasswordPolicy) Validate(password, email string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.minLength || length > p.maxLength {
+		return repository.ErrInvalid
+	}
+	if email != "" && strings.EqualFold(password, email) {
+		return repository.ErrInvalid
+	}
+	if _, ok := p.breached[password]; ok {
+		return repository.ErrInvalid
+	}
+	if _, ok := p.breached[strings.ToLower(password)]; ok {
+		return repository.ErrInvalid
+	}
+	return nil
+}
+
+// LoadBreachedPasswords reads a newline separated list of known leaked
+// passwords. An empty path disables the check.
+func LoadBreachedPasswords(path string) (map[string]struct{}, error) {
+	breached := make(map[string]struct{})
+	if path == "" {
+		return breached, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breached[line] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return breached, nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"islamdiplom/internal/repository"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := NewPasswordPolicy(8, 16, map[string]struct{}{"password123": {}})
+
+	tests := []struct {
+		name     string
+		password string
+		email    string
+		wantErr  bool
+	}{
+		{name: "acceptable", password: "tr0ub4dor&3", email: "user@example.com"},
+		{name: "too short", password: "short", wantErr: true},
+		{name: "exactly the minimum", password: "abcdefgh"},
+		{name: "too long", password: strings.Repeat("a", 17), wantErr: true},
+		{name: "length counts runes, not bytes", password: strings.Repeat("ж", 16)},
+		{name: "Cyrillic over the limit", password: strings.Repeat("ж", 17), wantErr: true},
+		{name: "same as the email", password: "User@Example.com", email: "user@example.com", wantErr: true},
+		{name: "breached", password: "password123", wantErr: true},
+		{name: "breached in another case", password: "PASSWORD123", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, tt.email)
+			if tt.wantErr {
+				if !errors.Is(err, repository.ErrInvalid) {
+					t.Fatalf("err = %v, want ErrInvalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v, want nil", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/logging"
+	"islamdiplom/internal/mailer"
+	"islamdiplom/internal/repository"
+)
+
+type PasswordService struct {
+	auth     *AuthService
+	users    repository.UserRepository
+	resets   repository.PasswordResetRepository
+	mailer   mailer.Mailer
+	resetTTL time.Duration
+	resetURL string
+	audit    *AuditLog
+}
+
+func NewPasswordService(auth *AuthService, users repository.UserRepository, resets repository.PasswordResetRepository, mail mailer.Mailer, resetTTL time.Duration, resetURL string, audit *AuditLog) *PasswordService {
+	return &PasswordService{
+		auth:     auth,
+		users:    users,
+		resets:   resets,
+		mailer:   mail,
+		resetTTL: resetTTL,
+		resetURL: resetURL,
+		audit:    audit,
+	}
+}
+
+// Forgot never reports whether the email is registered, neither in its result
+// nor in its timing: the token and the email are handled in the background.
+func (s *PasswordService) Forgot(ctx context.Context, email string) error {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	user, err := s.users.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	go s.sendReset(context.WithoutCancel(ctx), user)
+	return nil
+}
+
+// sendReset logs failures rather than returning them: an error only known
+// addresses can produce would tell callers which emails have accounts.
+func (s *PasswordService) sendReset(ctx context.Context, user domain.User) {
+	logger := logging.FromContext(ctx).With(zap.String("userId", user.ID.String()))
+
+	raw, err := randomToken()
+	if err != nil {
+		logger.Error("password reset token failed", zap.Error(err))
+		return
+	}
+	if err := s.resets.Create(ctx, domain.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(s.resetTTL).UTC(),
+	}); err != nil {
+		logger.Error("password reset token failed", zap.Error(err))
+		return
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: "Чтобы задать новый пароль, перейдите по ссылке:\n" +
+			withToken(s.resetURL, raw) + "\n\n" +
+			"Ссылка действует " + s.resetTTL.String() + ". Если вы не запрашивали сброс, просто проигнорируйте это письмо.",
+	})
+	if err != nil {
+		logger.Error("password reset email failed", zap.Error(err))
+	}
+}
+
+func (s *PasswordService) Reset(ctx context.Context, token, password string) error {
+	if token == "" || password == "" {
+		return repository.ErrInvalid
+	}
+	if err := s.auth.policy.Validate(password, ""); err != nil {
+		return err
+	}
+
+	userID, err := s.resets.Consume(ctx, hashToken(token), time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return repository.ErrUnauthorized
+		}
+		return err
+	}
+
+	if err := s.setPassword(ctx, userID, password); err != nil {
+		return err
+	}
+	if err := s.resets.InvalidateForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.auth.revokeAllSessions(ctx, userID); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditReset, domain.AuditEntityPassword, userID.String(), nil, nil)
+	return nil
+}
+
+// Change keeps the caller signed in with a fresh token pair while every other
+// session is revoked.
+func (s *PasswordService) Change(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string, client ClientInfo) (TokenPair, error) {
+	if currentPassword == "" || newPassword == "" {
+		return TokenPair{}, repository.ErrInvalid
+	}
+
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	ok, _, err := s.auth.hasher.Verify(user.PasswordHash, currentPassword)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if !ok {
+		return TokenPair{}, repository.ErrUnauthorized
+	}
+	if err := s.auth.policy.Validate(newPassword, user.Email); err != nil {
+		return TokenPair{}, err
+	}
+
+	if err := s.setPassword(ctx, userID, newPassword); err != nil {
+		return TokenPair{}, err
+	}
+	if err := s.auth.revokeAllSessions(ctx, userID); err != nil {
+		return TokenPair{}, err
+	}
+	s.audit.Record(ctx, domain.AuditUpdate, domain.AuditEntityPassword, userID.String(), nil, nil)
+	return s.auth.issueTokens(ctx, userID, client)
+}
+
+func (s *PasswordService) setPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	hash, err := s.auth.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	return s.users.UpdatePassword(ctx, userID, hash)
+}
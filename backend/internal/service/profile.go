@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+const maxProfileNameLength = 100
+
+type ProfileInput struct {
+	Name              string
+	Phone             string
+	PreferredLanguage string
+}
+
+type ProfileService struct {
+	users    repository.UserRepository
+	bookings repository.BookingRepository
+	events   repository.EventRepository
+	hasher   *PasswordHasher
+	audit    *AuditLog
+}
+
+func NewProfileService(users repository.UserRepository, bookings repository.BookingRepository, events repository.EventRepository, hasher *PasswordHasher, audit *AuditLog) *ProfileService {
+	return &ProfileService{users: users, bookings: bookings, events: events, hasher: hasher, audit: audit}
+}
+
+func (s *ProfileService) Update(ctx context.Context, userID uuid.UUID, input ProfileInput) (domain.User, error) {
+	name := strings.TrimSpace(input.Name)
+	if utf8.RuneCountInString(name) > maxProfileNameLength {
+		return domain.User{}, repository.ErrInvalid
+	}
+	phone, err := normalizePhone(input.Phone)
+	if err != nil {
+		return domain.User{}, err
+	}
+	language := strings.ToLower(strings.TrimSpace(input.PreferredLanguage))
+	if !isSupportedLanguage(language) {
+		return domain.User{}, repository.ErrInvalid
+	}
+
+	before, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	updated, err := s.users.UpdateProfile(ctx, domain.User{
+		ID:                userID,
+		Name:              name,
+		Phone:             phone,
+		PreferredLanguage: language,
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	s.audit.Record(ctx, domain.AuditUpdate, domain.AuditEntityUser, userID.String(), nil, profileChanges(before, updated))
+	return updated, nil
+}
+
+// profileChanges names the fields an update changed without their values:
+// the audit log is append-only, so names and phone numbers written to it
+// could never be erased when the account is deleted.
+func profileChanges(before, after domain.User) map[string][]string {
+	changed := make([]string, 0, 3)
+	if before.Name != after.Name {
+		changed = append(changed, "name")
+	}
+	if before.Phone != after.Phone {
+		changed = append(changed, "phone")
+	}
+	if before.PreferredLanguage != after.PreferredLanguage {
+		changed = append(changed, "preferredLanguage")
+	}
+	return map[string][]string{"changed": changed}
+}
+
+// Export bundles everything stored about the user. Each booked seat is
+// listed as a ticket with the event it admits to.
+func (s *ProfileService) Export(ctx context.Context, userID uuid.UUID) (domain.AccountExport, error) {
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return domain.AccountExport{}, err
+	}
+	bookings, err := s.bookings.ListByUser(ctx, userID)
+	if err != nil {
+		return domain.AccountExport{}, err
+	}
+
+	events := make(map[uuid.UUID]domain.Event)
+	tickets := make([]domain.Ticket, 0)
+	for _, booking := range bookings {
+		event, ok := events[booking.EventID]
+		if !ok {
+			event, err = s.events.Get(ctx, booking.EventID)
+			if err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return domain.AccountExport{}, err
+			}
+			events[booking.EventID] = event
+		}
+		for _, seat := range booking.Seats {
+			tickets = append(tickets, domain.Ticket{
+				BookingID:  booking.ID,
+				EventID:    booking.EventID,
+				EventTitle: event.Title,
+				StartAt:    event.StartAt,
+				Seat:       seat,
+				Status:     booking.Status,
+			})
+		}
+	}
+	if bookings == nil {
+		bookings = []domain.Booking{}
+	}
+
+	return domain.AccountExport{
+		ExportedAt: time.Now().UTC(),
+		Profile:    user,
+		Bookings:   bookings,
+		Tickets:    tickets,
+	}, nil
+}
+
+// Delete anonymizes the account. Accounts with a password must confirm it;
+// accounts created through an identity provider have none to confirm.
+func (s *ProfileService) Delete(ctx context.Context, userID uuid.UUID, password string) error {
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash != "" {
+		ok, _, err := s.hasher.Verify(user.PasswordHash, password)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return repository.ErrUnauthorized
+		}
+	}
+
+	if err := s.users.Anonymize(ctx, userID, time.Now().UTC()); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditDelete, domain.AuditEntityUser, userID.String(), nil, nil)
+	return nil
+}
+
+// normalizePhone keeps the leading plus and digits, dropping the spaces,
+// dashes and brackets people type.
+func normalizePhone(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+
+	var b strings.Builder
+	for i, r := range raw {
+		switch {
+		case unicode.IsDigit(r) && r < utf8.RuneSelf:
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')':
+		default:
+			return "", repository.ErrInvalid
+		}
+	}
+
+	phone := b.String()
+	digits := len(strings.TrimPrefix(phone, "+"))
+	if digits < 10 || digits > 15 {
+		return "", repository.ErrInvalid
+	}
+	return phone, nil
+}
+
+func isSupportedLanguage(language string) bool {
+	for _, supported := range domain.Languages {
+		if language == supported {
+			return true
+		}
+	}
+	return false
+}
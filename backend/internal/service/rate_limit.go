@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/logging"
+	"islamdiplom/internal/repository"
+)
+
+type RateLimiter struct {
+	store    repository.RateLimitRepository
+	policies map[string]domain.RateLimitPolicy
+}
+
+// NewRateLimiter keeps only enabled policies. Store failures are logged and
+// the request is let through, so an unavailable store does not take the API
+// down with it.
+func NewRateLimiter(store repository.RateLimitRepository, policies []domain.RateLimitPolicy) *RateLimiter {
+	byName := make(map[string]domain.RateLimitPolicy, len(policies))
+	for _, policy := range policies {
+		if policy.Enabled() {
+			byName[policy.Name] = policy
+		}
+	}
+	return &RateLimiter{store: store, policies: byName}
+}
+
+// Take spends a token from subject's bucket under the named policy. The
+// second result is false when no limit applies to the request.
+func (l *RateLimiter) Take(ctx context.Context, name, subject string) (domain.RateLimitPolicy, domain.RateLimitDecision, bool) {
+	policy, ok := l.policies[name]
+	if !ok {
+		return domain.RateLimitPolicy{}, domain.RateLimitDecision{}, false
+	}
+	decision, err := l.store.Take(ctx, name+":"+subject, policy, time.Now().UTC())
+	if err != nil {
+		logging.FromContext(ctx).Warn("rate limit store error", zap.String("policy", name), zap.Error(err))
+		return domain.RateLimitPolicy{}, domain.RateLimitDecision{}, false
+	}
+	return policy, decision, true
+}
+
+// Prune drops buckets idle for longer than the widest window; they would
+// have refilled completely anyway.
+func (l *RateLimiter) Prune(ctx context.Context) error {
+	var widest time.Duration
+	for _, policy := range l.policies {
+		if policy.Window > widest {
+			widest = policy.Window
+		}
+	}
+	return l.store.Prune(ctx, time.Now().UTC().Add(-widest))
+}
+
+// ParseRateLimitPolicy reads "<limit>/<window>", e.g. "10/1m". "off"
+// disables the policy.
+func ParseRateLimitPolicy(name, spec string) (domain.RateLimitPolicy, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "off" {
+		return domain.RateLimitPolicy{Name: name}, nil
+	}
+	rawLimit, rawWindow, ok := strings.Cut(spec, "/")
+	if !ok {
+		return domain.RateLimitPolicy{}, fmt.Errorf("rate limit %q: expected <limit>/<window>", spec)
+	}
+	limit, err := strconv.Atoi(rawLimit)
+	if err != nil || limit <= 0 {
+		return domain.RateLimitPolicy{}, fmt.Errorf("rate limit %q: invalid limit", spec)
+	}
+	window, err := time.ParseDuration(rawWindow)
+	if err != nil || window <= 0 {
+		return domain.RateLimitPolicy{}, fmt.Errorf("rate limit %q: invalid window", spec)
+	}
+	return domain.RateLimitPolicy{Name: name, Limit: limit, Window: window}, nil
+}
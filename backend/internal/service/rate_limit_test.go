@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"islamdiplom/internal/domain"
+)
+
+func TestParseRateLimitPolicy(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    domain.RateLimitPolicy
+		wantErr bool
+	}{
+		{spec: "10/1m", want: domain.RateLimitPolicy{Name: "auth", Limit: 10, Window: time.Minute}},
+		{spec: " 300/1h30m ", want: domain.RateLimitPolicy{Name: "auth", Limit: 300, Window: 90 * time.Minute}},
+		{spec: "off", want: domain.RateLimitPolicy{Name: "auth"}},
+		{spec: "", wantErr: true},
+		{spec: "10", wantErr: true},
+		{spec: "0/1m", wantErr: true},
+		{spec: "-1/1m", wantErr: true},
+		{spec: "ten/1m", wantErr: true},
+		{spec: "10/0s", wantErr: true},
+		{spec: "10/-1m", wantErr: true},
+		{spec: "10/minute", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseRateLimitPolicy("auth", tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRateLimitPolicy(%q) = %+v, want an error", tt.spec, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRateLimitPolicy(%q): %v", tt.spec, err)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseRateLimitPolicy(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+
+	if policy, _ := ParseRateLimitPolicy("auth", "off"); policy.Enabled() {
+		t.Fatal(`"off" policy is enabled`)
+	}
+}
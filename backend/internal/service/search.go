@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	maxSearchTerms     = 8
+	highlightStart     = "<mark>"
+	highlightStop      = "</mark>"
+)
+
+type SearchService struct {
+	repo repository.SearchRepository
+}
+
+func NewSearchService(repo repository.SearchRepository) *SearchService {
+	return &SearchService{repo: repo}
+}
+
+func (s *SearchService) Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error) {
+	tsQuery := buildPrefixQuery(query)
+	if tsQuery == "" {
+		return nil, repository.ErrInvalid
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	results, err := s.repo.Search(ctx, tsQuery, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Snippet = escapeSnippet(results[i].Snippet)
+	}
+	return results, nil
+}
+
+// Only letters and digits survive, so user input can never inject tsquery
+// operators; every term becomes a prefix match for as-you-type search.
+func buildPrefixQuery(raw string) string {
+	terms := strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+func escapeSnippet(snippet string) string {
+	parts := strings.Split(snippet, highlightStart)
+	for i, part := range parts {
+		inner := strings.Split(part, highlightStop)
+		for j := range inner {
+			inner[j] = html.EscapeString(inner[j])
+		}
+		parts[i] = strings.Join(inner, highlightStop)
+	}
+	return strings.Join(parts, highlightStart)
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"time"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/repository"
+)
+
+var wallClockLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ParseEventTime reads an event time. Times with an explicit offset are
+// absolute; times without one are wall-clock readings in the venue's zone.
+// Wall-clock times that fall into a DST gap do not exist in that zone and are
+// rejected instead of being silently shifted; times repeated by a fall-back
+// transition mean their first occurrence, as in RFC 5545.
+func ParseEventTime(raw string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+	for _, layout := range wallClockLayouts {
+		t, err := time.ParseInLocation(layout, raw, loc)
+		if err != nil {
+			continue
+		}
+		if t.Format(layout) != raw {
+			return time.Time{}, repository.ErrInvalid
+		}
+		return firstOccurrence(t, layout, raw, loc).UTC(), nil
+	}
+	return time.Time{}, repository.ErrInvalid
+}
+
+// firstOccurrence picks the earlier instant for an ambiguous wall-clock time;
+// time.ParseInLocation leaves that choice unspecified.
+func firstOccurrence(t time.Time, layout, raw string, loc *time.Location) time.Time {
+	wall, err := time.Parse(layout, raw)
+	if err != nil {
+		return t
+	}
+	first := t
+	for _, probe := range []time.Time{t.Add(-12 * time.Hour), t.Add(12 * time.Hour)} {
+		_, offset := probe.In(loc).Zone()
+		candidate := wall.Add(-time.Duration(offset) * time.Second)
+		if candidate.Before(first) && candidate.In(loc).Format(layout) == raw {
+			first = candidate
+		}
+	}
+	return first
+}
+
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func localizeEvent(event domain.Event, loc *time.Location) domain.Event {
+	event.StartAt = event.StartAt.UTC()
+	event.EndAt = event.EndAt.UTC()
+	event.Timezone = loc.String()
+	event.StartAtLocal = event.StartAt.In(loc).Format(time.RFC3339)
+	event.EndAtLocal = event.EndAt.In(loc).Format(time.RFC3339)
+	return event
+}
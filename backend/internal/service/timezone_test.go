@@ -0,0 +1,151 @@
+package service
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"islamdiplom/internal/domain"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestParseEventTime(t *testing.T) {
+	almaty := mustLoad(t, "Asia/Almaty")
+	berlin := mustLoad(t, "Europe/Berlin")
+
+	tests := []struct {
+		name    string
+		raw     string
+		loc     *time.Location
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "explicit offset ignores the venue zone",
+			raw:  "2025-06-01T19:00:00+03:00",
+			loc:  almaty,
+			want: "2025-06-01T16:00:00Z",
+		},
+		{
+			name: "Almaty wall clock",
+			raw:  "2025-06-01T19:00",
+			loc:  almaty,
+			want: "2025-06-01T14:00:00Z",
+		},
+		{
+			name: "Almaty wall clock with seconds and a space",
+			raw:  "2025-06-01 19:00:30",
+			loc:  almaty,
+			want: "2025-06-01T14:00:30Z",
+		},
+		{
+			name:    "DST gap is rejected",
+			raw:     "2024-03-31T02:30",
+			loc:     berlin,
+			wantErr: true,
+		},
+		{
+			name: "fall-back ambiguity resolves to the first occurrence",
+			raw:  "2024-10-27T02:30",
+			loc:  berlin,
+			want: "2024-10-27T00:30:00Z",
+		},
+		{
+			name: "just after the fall-back overlap",
+			raw:  "2024-10-27T03:00",
+			loc:  berlin,
+			want: "2024-10-27T02:00:00Z",
+		},
+		{
+			name:    "garbage",
+			raw:     "tomorrow evening",
+			loc:     almaty,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEventTime(tt.raw, tt.loc)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseEventTime(%q) = %v, want error", tt.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseEventTime(%q): %v", tt.raw, err)
+			}
+			if got.Location() != time.UTC {
+				t.Errorf("location = %v, want UTC", got.Location())
+			}
+			if s := got.Format(time.RFC3339); s != tt.want {
+				t.Errorf("ParseEventTime(%q) = %s, want %s", tt.raw, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalizeEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		zone      string
+		start     time.Time
+		end       time.Time
+		wantStart string
+		wantEnd   string
+	}{
+		{
+			name:      "Almaty",
+			zone:      "Asia/Almaty",
+			start:     time.Date(2025, 6, 1, 14, 0, 0, 0, time.UTC),
+			end:       time.Date(2025, 6, 1, 16, 30, 0, 0, time.UTC),
+			wantStart: "2025-06-01T19:00:00+05:00",
+			wantEnd:   "2025-06-01T21:30:00+05:00",
+		},
+		{
+			name:      "across the Berlin fall-back",
+			zone:      "Europe/Berlin",
+			start:     time.Date(2024, 10, 26, 23, 0, 0, 0, time.UTC),
+			end:       time.Date(2024, 10, 27, 3, 0, 0, 0, time.UTC),
+			wantStart: "2024-10-27T01:00:00+02:00",
+			wantEnd:   "2024-10-27T04:00:00+01:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLoad(t, tt.zone)
+			event := localizeEvent(domain.Event{StartAt: tt.start.In(loc), EndAt: tt.end}, loc)
+			if event.Timezone != tt.zone {
+				t.Errorf("Timezone = %q, want %q", event.Timezone, tt.zone)
+			}
+			if event.StartAtLocal != tt.wantStart || event.EndAtLocal != tt.wantEnd {
+				t.Errorf("local = %s..%s, want %s..%s", event.StartAtLocal, event.EndAtLocal, tt.wantStart, tt.wantEnd)
+			}
+			if event.StartAt.Location() != time.UTC || !event.StartAt.Equal(tt.start) {
+				t.Errorf("StartAt = %v, want %v in UTC", event.StartAt, tt.start)
+			}
+		})
+	}
+}
+
+func TestParseEventTimeRoundTrip(t *testing.T) {
+	loc := mustLoad(t, "Asia/Almaty")
+	parsed, err := ParseEventTime("2025-12-31T23:30", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := localizeEvent(domain.Event{StartAt: parsed, EndAt: parsed.Add(time.Hour)}, loc)
+	if event.StartAtLocal != "2025-12-31T23:30:00+05:00" {
+		t.Errorf("StartAtLocal = %s", event.StartAtLocal)
+	}
+}
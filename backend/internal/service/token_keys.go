@@ -0,0 +1,261 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"islamdiplom/internal/repository"
+)
+
+const minRSAKeyBits = 2048
+
+// keyTimeLayout is the UTC creation time every key file name starts with,
+// e.g. 20261019T120000Z-main.pem.
+const keyTimeLayout = "20060102T150405Z"
+
+type TokenKey struct {
+	ID       string
+	Method   jwt.SigningMethod
+	Private  crypto.Signer
+	Public   crypto.PublicKey
+	ActiveAt time.Time
+}
+
+// JWK is the public half of a signing key as published in the JWKS document.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// TokenKeys signs and verifies JWTs. With a key directory every *.pem file
+// (RSA or Ed25519 private key, PKCS#8 or PKCS#1) is a key whose ID is the
+// file name. The name starts with the key's creation time in keyTimeLayout;
+// file timestamps are not used because copies and mounted secrets do not keep
+// them. A new key is published in the JWKS right away but signs tokens only
+// publishDelay after its creation time, so other services have fetched it by
+// then. Old keys keep verifying until their files are removed. Without a
+// directory tokens are signed with the shared HMAC secret.
+type TokenKeys struct {
+	mu           sync.RWMutex
+	dir          string
+	publishDelay time.Duration
+	keys         map[string]TokenKey
+	secret       []byte
+}
+
+func NewHMACTokenKeys(secret string) *TokenKeys {
+	return &TokenKeys{secret: []byte(secret)}
+}
+
+func LoadTokenKeys(dir string, publishDelay time.Duration) (*TokenKeys, error) {
+	keys := &TokenKeys{dir: dir, publishDelay: publishDelay}
+	if err := keys.Reload(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Reload rereads the key directory. On error the previous keys stay in use.
+func (k *TokenKeys) Reload() error {
+	if k.dir == "" {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(k.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	loaded := make(map[string]TokenKey, len(paths))
+	for _, path := range paths {
+		key, err := readTokenKey(path, k.publishDelay)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		loaded[key.ID] = key
+	}
+	if len(loaded) == 0 {
+		return fmt.Errorf("no signing keys in %s", k.dir)
+	}
+
+	k.mu.Lock()
+	k.keys = loaded
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *TokenKeys) Sign(claims jwt.MapClaims) (string, error) {
+	if k.dir == "" {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.secret)
+	}
+
+	key, ok := k.signingKey(time.Now())
+	if !ok {
+		return "", errors.New("no signing key")
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+func (k *TokenKeys) Verify(token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, k.lookup)
+	if err != nil || !parsed.Valid {
+		return nil, repository.ErrUnauthorized
+	}
+	return claims, nil
+}
+
+// UsesDefaultSecret reports whether tokens are signed with the given
+// placeholder secret, which anyone reading the source could forge.
+func (k *TokenKeys) UsesDefaultSecret(placeholder string) bool {
+	return k.dir == "" && string(k.secret) == placeholder
+}
+
+func (k *TokenKeys) JWKS() []JWK {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := make([]JWK, 0, len(ids))
+	for _, id := range ids {
+		key := k.keys[id]
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		set = append(set, jwk)
+	}
+	return set
+}
+
+func (k *TokenKeys) lookup(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if k.dir == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || kid != "" {
+			return nil, repository.ErrUnauthorized
+		}
+		return k.secret, nil
+	}
+
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	k.mu.RUnlock()
+	if !ok || token.Method.Alg() != key.Method.Alg() {
+		return nil, repository.ErrUnauthorized
+	}
+	return key.Public, nil
+}
+
+// signingKey picks the most recently activated key. Right after the first
+// start nothing is active yet, so the key closest to activation is used.
+func (k *TokenKeys) signingKey(now time.Time) (TokenKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var active, pending TokenKey
+	var hasActive, hasPending bool
+	for _, key := range k.keys {
+		if !key.ActiveAt.After(now) {
+			if !hasActive || key.ActiveAt.After(active.ActiveAt) || (key.ActiveAt.Equal(active.ActiveAt) && key.ID > active.ID) {
+				active, hasActive = key, true
+			}
+			continue
+		}
+		if !hasPending || key.ActiveAt.Before(pending.ActiveAt) {
+			pending, hasPending = key, true
+		}
+	}
+	if hasActive {
+		return active, true
+	}
+	return pending, hasPending
+}
+
+func readTokenKey(path string, publishDelay time.Duration) (TokenKey, error) {
+	id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	stamp, _, _ := strings.Cut(id, "-")
+	createdAt, err := time.Parse(keyTimeLayout, stamp)
+	if err != nil {
+		return TokenKey{}, fmt.Errorf("file name must start with the creation time as %s", keyTimeLayout)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TokenKey{}, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return TokenKey{}, errors.New("no PEM block")
+	}
+
+	var private any
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return TokenKey{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return TokenKey{}, err
+	}
+
+	key := TokenKey{
+		ID:       id,
+		ActiveAt: createdAt.Add(publishDelay),
+	}
+	switch signer := private.(type) {
+	case *rsa.PrivateKey:
+		if signer.N.BitLen() < minRSAKeyBits {
+			return TokenKey{}, fmt.Errorf("RSA key shorter than %d bits", minRSAKeyBits)
+		}
+		key.Method = jwt.SigningMethodRS256
+		key.Private = signer
+		key.Public = &signer.PublicKey
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.Private = signer
+		key.Public = signer.Public()
+	default:
+		return TokenKey{}, errors.New("unsupported key type")
+	}
+	return key, nil
+}
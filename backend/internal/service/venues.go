@@ -2,18 +2,27 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 	"islamdiplom/internal/domain"
+	"islamdiplom/internal/logging"
 	"islamdiplom/internal/repository"
 )
 
+const defaultTimezone = "Asia/Almaty"
+
+var errUnknownTimezone = repository.Invalid("timezone", "unknown", "expected an IANA time zone name")
+
 type VenueService struct {
-	repo repository.VenueRepository
+	repo  repository.VenueRepository
+	halls repository.HallRepository
+	audit *AuditLog
 }
 
-func NewVenueService(repo repository.VenueRepository) *VenueService {
-	return &VenueService{repo: repo}
+func NewVenueService(repo repository.VenueRepository, halls repository.HallRepository, audit *AuditLog) *VenueService {
+	return &VenueService{repo: repo, halls: halls, audit: audit}
 }
 
 func (s *VenueService) List(ctx context.Context) ([]domain.Venue, error) {
@@ -25,13 +34,80 @@ func (s *VenueService) Get(ctx context.Context, id uuid.UUID) (domain.Venue, err
 }
 
 func (s *VenueService) Create(ctx context.Context, venue domain.Venue) (domain.Venue, error) {
-	return s.repo.Create(ctx, venue)
+	venue, err := normalizeVenue(venue)
+	if err != nil {
+		return domain.Venue{}, err
+	}
+	created, err := s.repo.Create(ctx, venue)
+	if err != nil {
+		return domain.Venue{}, err
+	}
+	hall, err := s.halls.Create(ctx, defaultHall(created.ID))
+	if err != nil {
+		// A venue without a hall cannot host events, so undo the insert.
+		if deleteErr := s.repo.Delete(context.WithoutCancel(ctx), created.ID); deleteErr != nil {
+			logging.FromContext(ctx).Error("venue rollback failed", zap.String("venueId", created.ID.String()), zap.Error(deleteErr))
+		}
+		return domain.Venue{}, err
+	}
+	s.audit.Record(ctx, domain.AuditCreate, domain.AuditEntityVenue, created.ID.String(), nil, created)
+	s.audit.Record(ctx, domain.AuditCreate, domain.AuditEntityHall, hall.ID.String(), nil, hall)
+	return created, nil
 }
 
 func (s *VenueService) Update(ctx context.Context, venue domain.Venue) (domain.Venue, error) {
-	return s.repo.Update(ctx, venue)
+	before, err := s.repo.Get(ctx, venue.ID)
+	if err != nil {
+		return domain.Venue{}, err
+	}
+	if venue.Version != 0 && venue.Version != before.Version {
+		return domain.Venue{}, repository.ErrPreconditionFailed
+	}
+	venue, err = normalizeVenue(venue)
+	if err != nil {
+		return domain.Venue{}, err
+	}
+	updated, err := s.repo.Update(ctx, venue)
+	if err != nil {
+		return domain.Venue{}, err
+	}
+	s.audit.Record(ctx, domain.AuditUpdate, domain.AuditEntityVenue, updated.ID.String(), before, updated)
+	return updated, nil
 }
 
 func (s *VenueService) Delete(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Delete(ctx, id)
+	before, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditDelete, domain.AuditEntityVenue, id.String(), before, nil)
+	return nil
+}
+
+func normalizeVenue(venue domain.Venue) (domain.Venue, error) {
+	if venue.Latitude == nil && venue.Longitude != nil {
+		return domain.Venue{}, repository.Invalid("latitude", "required", "latitude and longitude go together")
+	}
+	if venue.Latitude != nil && venue.Longitude == nil {
+		return domain.Venue{}, repository.Invalid("longitude", "required", "latitude and longitude go together")
+	}
+	if venue.Latitude != nil && (*venue.Latitude < -90 || *venue.Latitude > 90) {
+		return domain.Venue{}, repository.Invalid("latitude", "out_of_range", "")
+	}
+	if venue.Longitude != nil && (*venue.Longitude < -180 || *venue.Longitude > 180) {
+		return domain.Venue{}, repository.Invalid("longitude", "out_of_range", "")
+	}
+	if venue.Timezone == "" {
+		venue.Timezone = defaultTimezone
+	}
+	if venue.Timezone == "Local" {
+		return domain.Venue{}, errUnknownTimezone
+	}
+	if _, err := time.LoadLocation(venue.Timezone); err != nil {
+		return domain.Venue{}, errUnknownTimezone
+	}
+	return venue, nil
 }
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"islamdiplom/internal/domain"
+	"islamdiplom/internal/mailer"
+	"islamdiplom/internal/repository"
+)
+
+const (
+	maxEmailLength          = 254
+	maxVerificationsPerDay  = 5
+	verificationWindowLimit = 24 * time.Hour
+)
+
+type VerificationService struct {
+	users          repository.UserRepository
+	tokens         repository.EmailVerificationRepository
+	mailer         mailer.Mailer
+	ttl            time.Duration
+	resendInterval time.Duration
+	verifyURL      string
+}
+
+func NewVerificationService(users repository.UserRepository, tokens repository.EmailVerificationRepository, mail mailer.Mailer, ttl, resendInterval time.Duration, verifyURL string) *VerificationService {
+	return &VerificationService{
+		users:          users,
+		tokens:         tokens,
+		mailer:         mail,
+		ttl:            ttl,
+		resendInterval: resendInterval,
+		verifyURL:      verifyURL,
+	}
+}
+
+func (s *VerificationService) Send(ctx context.Context, user domain.User) error {
+	raw, err := randomToken()
+	if err != nil {
+		return err
+	}
+	if err := s.tokens.Create(ctx, domain.EmailVerificationToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(s.ttl).UTC(),
+	}); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Подтверждение адреса электронной почты",
+		Body: "Чтобы подтвердить адрес, перейдите по ссылке:\n" +
+			withToken(s.verifyURL, raw) + "\n\n" +
+			"Ссылка действует " + s.ttl.String() + ".",
+	})
+}
+
+func (s *VerificationService) Verify(ctx context.Context, token string) error {
+	if token == "" {
+		return repository.ErrInvalid
+	}
+
+	now := time.Now().UTC()
+	userID, err := s.tokens.Consume(ctx, hashToken(token), now)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return repository.ErrUnauthorized
+		}
+		return err
+	}
+
+	if err := s.users.MarkEmailVerified(ctx, userID, now); err != nil {
+		return err
+	}
+	return s.tokens.InvalidateForUser(ctx, userID)
+}
+
+func (s *VerificationService) Resend(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	recent, err := s.tokens.ListCreatedSince(ctx, userID, now.Add(-verificationWindowLimit))
+	if err != nil {
+		return err
+	}
+	if len(recent) >= maxVerificationsPerDay {
+		return repository.ErrTooManyRequests
+	}
+	if len(recent) > 0 && now.Sub(recent[0]) < s.resendInterval {
+		return repository.ErrTooManyRequests
+	}
+
+	return s.Send(ctx, user)
+}
+
+func normalizeEmail(raw string) (string, error) {
+	email := strings.TrimSpace(strings.ToLower(raw))
+	if email == "" || len(email) > maxEmailLength {
+		return "", repository.ErrInvalid
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || address.Name != "" {
+		return "", repository.ErrInvalid
+	}
+	_, host, ok := strings.Cut(email, "@")
+	if !ok || !strings.Contains(host, ".") || strings.HasPrefix(host, ".") || strings.HasSuffix(host, ".") {
+		return "", repository.ErrInvalid
+	}
+	return email, nil
+}
+
+func withToken(base, token string) string {
+	link, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
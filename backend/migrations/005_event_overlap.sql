@@ -0,0 +1,8 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+ALTER TABLE events
+  ADD CONSTRAINT events_time_range_check CHECK (end_at > start_at);
+
+ALTER TABLE events
+  ADD CONSTRAINT events_venue_no_overlap
+  EXCLUDE USING gist (venue_id WITH =, tstzrange(start_at, end_at, '[)') WITH &&);
@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS halls (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  venue_id uuid NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+  name text NOT NULL,
+  capacity integer NOT NULL CHECK (capacity > 0),
+  layout jsonb NOT NULL DEFAULT '[]'::jsonb,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (venue_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_halls_venue_id ON halls (venue_id);
+
+INSERT INTO halls (venue_id, name, capacity, layout)
+SELECT
+  id,
+  'Основной зал',
+  60,
+  '[{"label":"A","seats":10},{"label":"B","seats":10},{"label":"C","seats":10},{"label":"D","seats":10},{"label":"E","seats":10},{"label":"F","seats":10}]'::jsonb
+FROM venues
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE events
+  ADD COLUMN IF NOT EXISTS hall_id uuid REFERENCES halls(id) ON DELETE RESTRICT;
+
+UPDATE events e
+SET hall_id = h.id
+FROM halls h
+WHERE h.venue_id = e.venue_id
+  AND h.name = 'Основной зал'
+  AND e.hall_id IS NULL;
+
+ALTER TABLE events
+  ALTER COLUMN hall_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_events_hall_id ON events (hall_id);
+
+ALTER TABLE events
+  DROP CONSTRAINT IF EXISTS events_venue_no_overlap;
+
+ALTER TABLE events
+  ADD CONSTRAINT events_hall_no_overlap
+  EXCLUDE USING gist (hall_id WITH =, tstzrange(start_at, end_at, '[)') WITH &&);
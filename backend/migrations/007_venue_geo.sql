@@ -0,0 +1,13 @@
+ALTER TABLE venues
+  ADD COLUMN IF NOT EXISTS city text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS latitude double precision,
+  ADD COLUMN IF NOT EXISTS longitude double precision,
+  ADD COLUMN IF NOT EXISTS timezone text NOT NULL DEFAULT 'Asia/Almaty';
+
+ALTER TABLE venues
+  ADD CONSTRAINT venues_latitude_check CHECK (latitude BETWEEN -90 AND 90),
+  ADD CONSTRAINT venues_longitude_check CHECK (longitude BETWEEN -180 AND 180),
+  ADD CONSTRAINT venues_coordinates_check CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+CREATE INDEX IF NOT EXISTS idx_venues_coordinates ON venues (latitude, longitude);
+CREATE INDEX IF NOT EXISTS idx_events_end_at ON events (end_at);
@@ -0,0 +1,25 @@
+ALTER TABLE events
+  ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+  ) STORED;
+
+ALTER TABLE venues
+  ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(address, '')), 'C') ||
+    setweight(to_tsvector('simple', coalesce(address, '')), 'C')
+  ) STORED;
+
+ALTER TABLE categories
+  ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A')
+  ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_events_search ON events USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS idx_venues_search ON venues USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS idx_categories_search ON categories USING gin (search_vector);
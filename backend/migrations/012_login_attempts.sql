@@ -0,0 +1,16 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'user';
+
+ALTER TABLE users
+  ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'organizer', 'admin'));
+
+CREATE TABLE IF NOT EXISTS failed_logins (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  email text NOT NULL,
+  ip text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  cleared_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_failed_logins_email ON failed_logins (email, created_at);
+CREATE INDEX IF NOT EXISTS idx_failed_logins_ip ON failed_logins (ip, created_at);
@@ -0,0 +1,13 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS name text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS phone text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS preferred_language text NOT NULL DEFAULT 'ru',
+  ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+-- Deleted accounts are anonymized instead of removed, and bookings must
+-- survive for accounting even if a user row is ever deleted by hand.
+ALTER TABLE bookings
+  DROP CONSTRAINT IF EXISTS bookings_user_id_fkey;
+
+ALTER TABLE bookings
+  ADD CONSTRAINT bookings_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
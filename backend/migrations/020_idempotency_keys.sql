@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  subject text NOT NULL,
+  key text NOT NULL,
+  fingerprint text NOT NULL,
+  status_code integer NOT NULL DEFAULT 0,
+  content_type text NOT NULL DEFAULT '',
+  body bytea,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  expires_at timestamptz NOT NULL,
+  PRIMARY KEY (subject, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
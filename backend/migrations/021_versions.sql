@@ -0,0 +1,2 @@
+ALTER TABLE events ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
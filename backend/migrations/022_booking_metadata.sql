@@ -0,0 +1,4 @@
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS metadata jsonb NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_metadata_object;
+ALTER TABLE bookings ADD CONSTRAINT bookings_metadata_object CHECK (jsonb_typeof(metadata) = 'object');
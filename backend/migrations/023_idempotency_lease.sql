@@ -0,0 +1,4 @@
+-- A reservation whose request never finished (the process died, or the
+-- response could not be stored) can be taken over by a retry once
+-- locked_until has passed.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS locked_until timestamptz NOT NULL DEFAULT now();
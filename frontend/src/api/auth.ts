@@ -3,9 +3,18 @@ import type { User } from '../types/user'
 
 type AuthResponse = {
   token: string
+  expiresAt: string
+  refreshToken: string
+  refreshExpiresAt: string
   user: User
 }
 
+type MFAChallenge = {
+  mfaRequired: true
+  mfaToken: string
+  expiresAt: string
+}
+
 export async function register(email: string, password: string) {
   return request<AuthResponse>('/api/auth/register', {
     method: 'POST',
@@ -17,7 +26,7 @@ export async function register(email: string, password: string) {
 }
 
 export async function login(email: string, password: string) {
-  return request<AuthResponse>('/api/auth/login', {
+  return request<AuthResponse | MFAChallenge>('/api/auth/login', {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json',
@@ -26,6 +35,26 @@ export async function login(email: string, password: string) {
   })
 }
 
+export async function verifyMFA(mfaToken: string, code: string) {
+  return request<AuthResponse>('/api/auth/mfa/verify', {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ mfaToken, code }),
+  })
+}
+
+export async function logout(refreshToken: string) {
+  return request<void>('/api/auth/logout', {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ refreshToken }),
+  })
+}
+
 export async function fetchProfile() {
   return request<User>('/api/profile')
 }
@@ -12,11 +12,18 @@ export async function listBookings() {
   return data.items
 }
 
-export async function createBooking(eventId: string, seats: string[]) {
+// Reusing idempotencyKey when retrying the same booking makes the server
+// return the original booking instead of creating a second one.
+export async function createBooking(
+  eventId: string,
+  seats: string[],
+  idempotencyKey: string = crypto.randomUUID(),
+) {
   return request<CreateBookingResponse>('/api/bookings', {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json',
+      'Idempotency-Key': idempotencyKey,
     },
     body: JSON.stringify({ eventId, seats }),
   })
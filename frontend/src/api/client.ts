@@ -6,22 +6,119 @@ const baseUrl =
     ? import.meta.env.VITE_API_BASE_URL
     : DEFAULT_BASE_URL
 
-export async function request<T>(path: string, init?: RequestInit): Promise<T> {
+export const STORAGE_TOKEN = 'token'
+export const STORAGE_REFRESH_TOKEN = 'refreshToken'
+
+export type FieldError = {
+  field: string
+  code: string
+  message?: string
+}
+
+export type Problem = {
+  type: string
+  title: string
+  status: number
+  code: string
+  detail?: string
+  instance?: string
+  requestId?: string
+  errors?: FieldError[]
+  eventId?: string
+  seats?: string[]
+  available?: number
+}
+
+const problemMessages: Record<string, string> = {
+  seats_taken: 'Часть выбранных мест уже занята.',
+  capacity_exceeded: 'Свободных мест недостаточно.',
+  event_overlap: 'Зал уже занят другим событием в это время.',
+  validation_failed: 'Проверьте правильность заполнения полей.',
+  precondition_failed: 'Данные уже изменил кто-то другой. Обновите страницу и повторите.',
+  too_many_requests: 'Слишком много запросов. Попробуйте позже.',
+}
+
+export class ApiError extends Error {
+  readonly status: number
+  readonly problem?: Problem
+
+  constructor(status: number, problem?: Problem) {
+    super(
+      (problem && problemMessages[problem.code]) ?? `Запрос не удался: ${status}`,
+    )
+    this.name = 'ApiError'
+    this.status = status
+    this.problem = problem
+  }
+}
+
+async function readProblem(response: Response): Promise<Problem | undefined> {
+  const contentType = response.headers.get('Content-Type') ?? ''
+  if (!contentType.includes('json')) {
+    return undefined
+  }
+  try {
+    return (await response.json()) as Problem
+  } catch {
+    return undefined
+  }
+}
+
+let refreshing: Promise<boolean> | null = null
+
+async function refreshAccessToken(): Promise<boolean> {
+  const refreshToken = window.localStorage.getItem(STORAGE_REFRESH_TOKEN)
+  if (!refreshToken) {
+    return false
+  }
+  const response = await fetch(new URL('/api/auth/refresh', baseUrl).toString(), {
+    method: 'POST',
+    headers: {
+      Accept: 'application/json',
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ refreshToken }),
+  })
+  if (!response.ok) {
+    window.localStorage.removeItem(STORAGE_TOKEN)
+    window.localStorage.removeItem(STORAGE_REFRESH_TOKEN)
+    return false
+  }
+  const data = (await response.json()) as { token: string; refreshToken: string }
+  window.localStorage.setItem(STORAGE_TOKEN, data.token)
+  window.localStorage.setItem(STORAGE_REFRESH_TOKEN, data.refreshToken)
+  return true
+}
+
+async function send(path: string, init?: RequestInit) {
   const url = new URL(path, baseUrl).toString()
-  const token = window.localStorage.getItem('token')
+  const token = window.localStorage.getItem(STORAGE_TOKEN)
   const headers = new Headers(init?.headers ?? {})
-  headers.set('Accept', 'application/json')
+  headers.set('Accept', 'application/json, application/problem+json')
   if (token) {
     headers.set('Authorization', `Bearer ${token}`)
     headers.set('X-Auth-Token', token)
   }
-  const response = await fetch(url, {
+  return fetch(url, {
     ...init,
     headers,
   })
+}
+
+export async function request<T>(path: string, init?: RequestInit): Promise<T> {
+  let response = await send(path, init)
+
+  if (response.status === 401 && !path.startsWith('/api/auth/')) {
+    refreshing ??= refreshAccessToken().finally(() => {
+      refreshing = null
+    })
+    if (await refreshing) {
+      response = await send(path, init)
+    }
+  }
 
   if (!response.ok) {
-    throw new Error(`Запрос не удался: ${response.status}`)
+    throw new ApiError(response.status, await readProblem(response))
   }
 
   if (response.status === 204) {
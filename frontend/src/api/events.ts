@@ -29,16 +29,32 @@ export async function createEvent(payload: EventPayload) {
   })
 }
 
-export async function updateEvent(id: string, payload: EventPayload) {
+// version is the one the caller last saw; the server answers 412 if the
+// event has been changed since.
+export async function updateEvent(id: string, version: number, payload: EventPayload) {
   return request<Event>(`/api/events/${id}`, {
     method: 'PUT',
     headers: {
       'Content-Type': 'application/json',
+      'If-Match': `"${version}"`,
     },
     body: JSON.stringify(payload),
   })
 }
 
+// patchEvent sends only the fields to change as a JSON Merge Patch, e.g.
+// { published: true }.
+export async function patchEvent(id: string, version: number, patch: Partial<EventPayload>) {
+  return request<Event>(`/api/events/${id}`, {
+    method: 'PATCH',
+    headers: {
+      'Content-Type': 'application/merge-patch+json',
+      'If-Match': `"${version}"`,
+    },
+    body: JSON.stringify(patch),
+  })
+}
+
 export async function deleteEvent(id: string) {
   return request<void>(`/api/events/${id}`, {
     method: 'DELETE',
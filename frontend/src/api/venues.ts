@@ -26,11 +26,12 @@ export async function createVenue(payload: CreateVenuePayload) {
   })
 }
 
-export async function updateVenue(id: string, payload: CreateVenuePayload) {
+export async function updateVenue(id: string, version: number, payload: CreateVenuePayload) {
   return request<Venue>(`/api/venues/${id}`, {
     method: 'PUT',
     headers: {
       'Content-Type': 'application/json',
+      'If-Match': `"${version}"`,
     },
     body: JSON.stringify(payload),
   })
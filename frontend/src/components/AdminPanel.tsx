@@ -89,7 +89,7 @@ function AdminPanel({ events, venues, onSaved }: Props) {
 
     try {
       if (selectedEvent) {
-        await updateEvent(selectedEvent.id, payload)
+        await updateEvent(selectedEvent.id, selectedEvent.version, payload)
       } else {
         await createEvent(payload)
       }
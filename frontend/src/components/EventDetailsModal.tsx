@@ -15,6 +15,7 @@ function EventDetailsModal({ event, onClose, onRequireAuth }: Props) {
   const { user } = useAuth()
   const seatPrice = 2500
   const [selectedSeats, setSelectedSeats] = useState<string[]>([])
+  const [bookingKey, setBookingKey] = useState(() => crypto.randomUUID())
   const [reservedSeats, setReservedSeats] = useState<string[]>([])
   const [status, setStatus] = useState<'idle' | 'loading' | 'success' | 'error'>('idle')
   const [error, setError] = useState<string | null>(null)
@@ -49,6 +50,11 @@ function EventDetailsModal({ event, onClose, onRequireAuth }: Props) {
     }
   }, [event.id])
 
+  const handleSeatsChange = (seats: string[]) => {
+    setSelectedSeats(seats)
+    setBookingKey(crypto.randomUUID())
+  }
+
   const handleBooking = async () => {
     if (!user) {
       onRequireAuth()
@@ -62,7 +68,7 @@ function EventDetailsModal({ event, onClose, onRequireAuth }: Props) {
     setStatus('loading')
     setError(null)
     try {
-      await createBooking(event.id, selectedSeats)
+      await createBooking(event.id, selectedSeats, bookingKey)
       setStatus('success')
     } catch (err) {
       const message = err instanceof Error ? err.message : 'Не удалось оформить бронь.'
@@ -96,7 +102,7 @@ function EventDetailsModal({ event, onClose, onRequireAuth }: Props) {
         <SeatPicker
           selected={selectedSeats}
           reserved={reservedSeats}
-          onChange={setSelectedSeats}
+          onChange={handleSeatsChange}
         />
 
         <div className="modal__booking">
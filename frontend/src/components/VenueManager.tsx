@@ -52,7 +52,7 @@ function VenueManager({ venues, onSaved }: Props) {
 
     try {
       if (selectedVenue) {
-        await updateVenue(selectedVenue.id, payload)
+        await updateVenue(selectedVenue.id, selectedVenue.version, payload)
       } else {
         await createVenue(payload)
       }
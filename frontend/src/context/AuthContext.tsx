@@ -1,5 +1,6 @@
 import { createContext, useCallback, useContext, useEffect, useMemo, useState } from 'react'
-import { fetchProfile, login, register } from '../api/auth'
+import { fetchProfile, login, logout, register, verifyMFA } from '../api/auth'
+import { STORAGE_REFRESH_TOKEN, STORAGE_TOKEN } from '../api/client'
 import type { User } from '../types/user'
 
 type AuthState = {
@@ -19,8 +20,6 @@ type AuthContextValue = {
 
 const AuthContext = createContext<AuthContextValue | undefined>(undefined)
 
-const STORAGE_TOKEN = 'token'
-
 export function AuthProvider({ children }: { children: React.ReactNode }) {
   const [state, setState] = useState<AuthState>({
     user: null,
@@ -28,12 +27,17 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
   })
   const [status, setStatus] = useState<AuthContextValue['status']>('idle')
 
-  const setToken = useCallback((token: string | null) => {
+  const setToken = useCallback((token: string | null, refreshToken: string | null = null) => {
     if (token) {
       window.localStorage.setItem(STORAGE_TOKEN, token)
     } else {
       window.localStorage.removeItem(STORAGE_TOKEN)
     }
+    if (refreshToken) {
+      window.localStorage.setItem(STORAGE_REFRESH_TOKEN, refreshToken)
+    } else {
+      window.localStorage.removeItem(STORAGE_REFRESH_TOKEN)
+    }
     setState((prev) => ({ ...prev, token }))
   }, [])
 
@@ -59,18 +63,29 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
   }, [refreshProfile])
 
   const handleLogin = useCallback(async (email: string, password: string) => {
-    const response = await login(email, password)
-    setToken(response.token)
+    let response = await login(email, password)
+    if ('mfaRequired' in response) {
+      const code = window.prompt('Введите код из приложения-аутентификатора или код восстановления')
+      if (!code) {
+        throw new Error('Требуется код подтверждения.')
+      }
+      response = await verifyMFA(response.mfaToken, code.trim())
+    }
+    setToken(response.token, response.refreshToken)
     setState({ token: response.token, user: response.user })
   }, [setToken])
 
   const handleRegister = useCallback(async (email: string, password: string) => {
     const response = await register(email, password)
-    setToken(response.token)
+    setToken(response.token, response.refreshToken)
     setState({ token: response.token, user: response.user })
   }, [setToken])
 
   const handleLogout = useCallback(() => {
+    const refreshToken = window.localStorage.getItem(STORAGE_REFRESH_TOKEN)
+    if (refreshToken) {
+      logout(refreshToken).catch(() => undefined)
+    }
     setToken(null)
     setState({ token: null, user: null })
   }, [setToken])
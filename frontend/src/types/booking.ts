@@ -5,6 +5,7 @@ export type Booking = {
   totalPrice: number
   currency: string
   seats: string[]
+  metadata: Record<string, unknown>
   createdAt: string
   updatedAt: string
 }
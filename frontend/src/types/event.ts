@@ -5,7 +5,12 @@ export type Event = {
   startAt: string
   endAt: string
   venueId: string
+  hallId: string
   published: boolean
+  version: number
+  timezone?: string
+  startAtLocal?: string
+  endAtLocal?: string
   createdAt: string
   updatedAt: string
 }
@@ -1,6 +1,11 @@
 export type User = {
   id: string
   email: string
+  emailVerifiedAt: string | null
+  role: string
+  name: string
+  phone: string
+  preferredLanguage: string
   createdAt: string
   updatedAt: string
 }
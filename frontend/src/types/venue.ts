@@ -2,6 +2,11 @@ export type Venue = {
   id: string
   name: string
   address: string
+  city: string
+  latitude: number | null
+  longitude: number | null
+  timezone: string
+  version: number
   createdAt: string
   updatedAt: string
 }